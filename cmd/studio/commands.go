@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/api"
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/state"
+)
+
+const usage = `Usage:
+  supabase-studio-go                      start the server
+  supabase-studio-go users add [-file f] [-email e] [-name n] [-role r] <username>
+                                          add or update a dashboard user (password read from stdin)
+  supabase-studio-go config validate [-file f]
+                                          check the config file and environment
+  supabase-studio-go state rekey [-old-key-file f]...
+                                          re-encrypt the dashboard state under SUPABASE_STUDIO_GO_STATE_KEY_FILE
+`
+
+func runCommand(args []string) int {
+	switch args[0] {
+	case "users":
+		return runUsersCommand(args[1:])
+	case "config":
+		return runConfigCommand(args[1:])
+	case "state":
+		return runStateCommand(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+func runUsersCommand(args []string) int {
+	if len(args) == 0 || args[0] != "add" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("users add", flag.ContinueOnError)
+	cfg, _, _ := config.LoadFile(os.Getenv(config.FileEnv))
+	file := flags.String("file", cfg.StudioUsersFile, "users file (defaults to SUPABASE_STUDIO_GO_USERS_FILE)")
+	email := flags.String("email", "", "email address shown in the dashboard")
+	name := flags.String("name", "", "display name shown in the dashboard")
+	role := flags.String("role", string(auth.RoleAdmin), "viewer, developer or admin")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	if strings.TrimSpace(*file) == "" {
+		fmt.Fprintln(os.Stderr, "no users file configured; pass -file or set SUPABASE_STUDIO_GO_USERS_FILE")
+		return 1
+	}
+
+	password, err := readPassword(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read password: %v\n", err)
+		return 1
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to hash password: %v\n", err)
+		return 1
+	}
+
+	store, err := auth.NewUserStore(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load users file: %v\n", err)
+		return 1
+	}
+	user := auth.User{
+		Username:     flags.Arg(0),
+		PasswordHash: hash,
+		Email:        *email,
+		Name:         *name,
+		Role:         auth.Role(*role),
+	}
+	if err := store.Put(user); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to save user: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("saved user %q to %s\n", strings.ToLower(strings.TrimSpace(user.Username)), *file)
+	return 0
+}
+
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	file := flags.String("file", os.Getenv(config.FileEnv), "config file (defaults to "+config.FileEnv+")")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, issues, err := config.LoadFile(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(issues) == 0 {
+		fmt.Println("configuration is valid")
+		return 0
+	}
+
+	for _, issue := range issues {
+		prefix := "error"
+		if issue.Insecure {
+			prefix = "insecure"
+		}
+		fmt.Printf("%s: %s\n", prefix, issue)
+	}
+	if cfg.Production && len(config.Insecure(issues)) > 0 {
+		fmt.Println("the server will refuse to start because production mode is enabled")
+	}
+	return 1
+}
+
+// rekeyAttempts bounds how often rekeying retries after a running server saved the state
+// in between.
+const rekeyAttempts = 3
+
+func runStateCommand(args []string) int {
+	if len(args) == 0 || args[0] != "rekey" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	cfg, _, err := config.LoadFile(os.Getenv(config.FileEnv))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	flags := flag.NewFlagSet("state rekey", flag.ContinueOnError)
+	flags.Func("old-key-file", "file holding a key the state may be encrypted with; repeatable, in addition to SUPABASE_STUDIO_GO_STATE_PREVIOUS_KEY_FILES", func(path string) error {
+		cfg.StatePreviousKeyFiles = append(cfg.StatePreviousKeyFiles, path)
+		return nil
+	})
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	if strings.TrimSpace(cfg.StateKeyFile) == "" {
+		fmt.Fprintln(os.Stderr, "no state key configured; set SUPABASE_STUDIO_GO_STATE_KEY_FILE to the new key")
+		return 1
+	}
+
+	store, err := api.OpenStateStore(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open the state store: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for attempt := 1; ; attempt++ {
+		doc, err := store.Load(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load the state: %v\n", err)
+			return 1
+		}
+		_, err = store.Save(ctx, doc)
+		if errors.Is(err, state.ErrConflict) && attempt < rekeyAttempts {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to save the state: %v\n", err)
+			return 1
+		}
+		break
+	}
+
+	fmt.Printf("re-encrypted the %s state under %s\n", cfg.StateBackend, cfg.StateKeyFile)
+	return 0
+}
+
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("empty password")
+	}
+	return password, nil
+}
@@ -1,32 +1,231 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/lifecycle"
+	"github.com/Gouryella/supabase-studio-go/internal/listener"
+	"github.com/Gouryella/supabase-studio-go/internal/logging"
 	"github.com/Gouryella/supabase-studio-go/internal/server"
+	"github.com/Gouryella/supabase-studio-go/internal/tlsconfig"
+	"github.com/Gouryella/supabase-studio-go/internal/tracing"
 )
 
 func main() {
-	cfg := config.Load()
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	configFile := os.Getenv(config.FileEnv)
+	cfg, issues, err := config.LoadFile(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "supabase-studio-go: %v\n", err)
+		os.Exit(2)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "supabase-studio-go: %v\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	for _, issue := range issues {
+		slog.Warn("configuration issue", "key", issue.Key, "problem", issue.Message)
+	}
+	if insecure := config.Insecure(issues); cfg.Production && len(insecure) > 0 {
+		slog.Error("refusing to start in production mode with insecure defaults; run `supabase-studio-go config validate` for details", "settings", len(insecure))
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:    cfg.TracingEndpoint,
+		ServiceName: cfg.TracingServiceName,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	background, stopBackground := context.WithCancel(context.Background())
+	// exit stops background work and flushes pending spans first, since os.Exit skips
+	// deferred calls.
+	exit := func(code int) {
+		stopBackground()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = shutdownTracing(ctx)
+		os.Exit(code)
+	}
 
-	handler := server.New(cfg)
+	holder := config.NewHolder(cfg, configFile)
+	go reloadOnHangup(holder)
+	handler := server.New(background, holder)
 
 	addr := cfg.ListenAddress
 	if addr == "" {
 		addr = ":3000"
 	}
 
+	drain := lifecycle.NewDrain()
+	baseContext := func(net.Listener) context.Context {
+		return lifecycle.WithDrain(context.Background(), drain)
+	}
 	srv := &http.Server{
 		Addr:              addr,
 		Handler:           handler,
 		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       baseContext,
+	}
+	servers := []*http.Server{srv}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		srv.TLSConfig, err = tlsconfig.New(tlsconfig.Options{
+			CertFile:     cfg.TLSCertFile,
+			KeyFile:      cfg.TLSKeyFile,
+			MinVersion:   cfg.TLSMinVersion,
+			ClientCAFile: cfg.TLSClientCAFile,
+		})
+		if err != nil {
+			slog.Error("failed to set up TLS", "error", err)
+			exit(1)
+		}
+		if cfg.TLSRedirectAddress != "" {
+			servers = append(servers, &http.Server{
+				Addr:              cfg.TLSRedirectAddress,
+				Handler:           server.NewHTTPSRedirect(cfg),
+				ReadHeaderTimeout: 10 * time.Second,
+				BaseContext:       baseContext,
+			})
+		}
+	}
+
+	listeners, err := listen(cfg, servers)
+	if err != nil {
+		slog.Error("failed to listen", "error", err)
+		exit(1)
+	}
+
+	if err := serve(drain, holder, servers, listeners); err != nil {
+		// A supervisor must see a failed server as a crash, not a clean stop.
+		slog.Error("server stopped", "error", err)
+		exit(1)
+	}
+	exit(0)
+}
+
+// listen opens a listener per server. Sockets passed in by systemd socket activation are
+// used first, in order, so the main listener is the first ListenStream= of the unit.
+func listen(cfg config.Config, servers []*http.Server) ([]net.Listener, error) {
+	mode, err := listener.ParseMode(cfg.ListenSocketMode)
+	if err != nil {
+		return nil, err
+	}
+	activated, err := listener.Activated()
+	if err != nil {
+		return nil, err
+	}
+	for _, extra := range activated[min(len(activated), len(servers)):] {
+		slog.Warn("ignoring extra systemd socket", "addr", extra.Addr().String())
+		_ = extra.Close()
+	}
+
+	listeners := make([]net.Listener, len(servers))
+	for i, srv := range servers {
+		if i < len(activated) {
+			listeners[i] = activated[i]
+			continue
+		}
+		if listeners[i], err = listener.Listen(srv.Addr, mode); err != nil {
+			for _, ln := range listeners[:i] {
+				_ = ln.Close()
+			}
+			return nil, fmt.Errorf("%s: %w", srv.Addr, err)
+		}
+	}
+	return listeners, nil
+}
+
+// reloadOnHangup re-reads the configuration on every SIGHUP. A failed reload keeps the
+// running configuration.
+func reloadOnHangup(holder *config.Holder) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		if _, _, err := holder.Reload(); err != nil {
+			slog.Error("failed to reload configuration, keeping the current one", "error", err)
+		}
+	}
+}
+
+// serve runs the servers until SIGINT or SIGTERM, then drains them: /readyz fails right away,
+// listeners close after the configured delay, and in-flight requests get until the
+// shutdown timeout to finish. Streaming handlers are asked to wrap up shortly before that.
+func serve(drain *lifecycle.Drain, holder *config.Holder, servers []*http.Server, listeners []net.Listener) error {
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	errs := make(chan error, len(servers))
+	for i, srv := range servers {
+		ln := listeners[i]
+		go func() {
+			if srv.TLSConfig != nil {
+				slog.Info("supabase-studio-go listening", "addr", ln.Addr().String(), "tls", true)
+				errs <- srv.ServeTLS(ln, "", "")
+				return
+			}
+			slog.Info("supabase-studio-go listening", "addr", ln.Addr().String())
+			errs <- srv.Serve(ln)
+		}()
+	}
+
+	select {
+	case err := <-errs:
+		for _, srv := range servers {
+			_ = srv.Close()
+		}
+		return err
+	case <-signals.Done():
+	}
+	stopSignals()
+
+	cfg := holder.Get()
+	timeout := cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
 	}
 
-	log.Printf("supabase-studio-go listening on %s", addr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("server stopped: %v", err)
+	drain.Start()
+	slog.Info("shutting down, draining connections", "delay", cfg.ShutdownDelay.String(), "timeout", timeout.String())
+	time.Sleep(cfg.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	grace := min(5*time.Second, timeout/2)
+	stopStreams := time.AfterFunc(timeout-grace, drain.Stop)
+	defer stopStreams.Stop()
+
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			slog.Warn("drain timeout exceeded, closing remaining connections", "addr", srv.Addr, "error", err)
+			_ = srv.Close()
+		}
+	}
+	for range servers {
+		if err := <-errs; err != nil && err != http.ErrServerClosed {
+			return err
+		}
 	}
+	slog.Info("server stopped")
+	return nil
 }
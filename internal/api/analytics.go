@@ -8,14 +8,16 @@ import (
 )
 
 func (api *API) retrieveAnalyticsData(r *http.Request, name, projectRef string, params map[string]string) (map[string]any, error) {
-	if api.cfg.LogflareURL == "" {
+	project := api.project(r)
+	if project.LogflareURL == "" {
 		return nil, errors.New("LOGFLARE_URL is required")
 	}
-	if api.cfg.LogflareToken == "" {
+	token := project.LogflareToken
+	if token == "" {
 		return nil, errors.New("LOGFLARE_PRIVATE_ACCESS_TOKEN is required")
 	}
 
-	base := api.cfg.LogflareURL
+	base := project.LogflareURL
 	endpoint, err := url.Parse(base)
 	if err != nil {
 		return nil, err
@@ -34,7 +36,7 @@ func (api *API) retrieveAnalyticsData(r *http.Request, name, projectRef string,
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("x-api-key", api.cfg.LogflareToken)
+	req.Header.Set("x-api-key", token)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/audit"
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// newAuditLogger keeps the audit log next to the state file unless a directory is configured.
+// Without either, auditing is disabled.
+func newAuditLogger(cfg config.Config) *audit.Logger {
+	dir := strings.TrimSpace(cfg.AuditLogDir)
+	if dir == "" && strings.TrimSpace(cfg.StateFilePath) != "" {
+		dir = filepath.Join(filepath.Dir(cfg.StateFilePath), "audit")
+	}
+	if dir == "" {
+		return nil
+	}
+
+	logger, err := audit.New(audit.Options{
+		Dir:      dir,
+		MaxSize:  int64(cfg.AuditLogMaxSizeMB) * 1024 * 1024,
+		MaxAge:   cfg.AuditLogMaxAge,
+		MaxFiles: cfg.AuditLogMaxFiles,
+	})
+	if err != nil {
+		slog.Error("failed to open audit log", "dir", dir, "error", err)
+		return nil
+	}
+	return logger
+}
+
+// recordAudit writes one audit entry for every request that is not a plain read,
+// including requests rejected by the route policy.
+func (api *API) recordAudit(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if api.audit == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			match, pattern := matchRoute(routes, r)
+			details := map[string]string{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(audit.WithDetails(r.Context(), details)))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			entry := audit.Entry{
+				Time:       start.UTC(),
+				RequestID:  middleware.GetReqID(r.Context()),
+				ClientIP:   r.RemoteAddr,
+				Role:       string(auth.RoleFromContext(r.Context())),
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Route:      strings.TrimSuffix(pattern, "/"),
+				Target:     auditTarget(match),
+				Status:     status,
+				Outcome:    audit.OutcomeForStatus(status),
+				DurationMS: time.Since(start).Milliseconds(),
+			}
+			if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+				entry.User = identity.Username
+			}
+			if len(details) > 0 {
+				entry.Details = details
+			}
+
+			if err := api.audit.Record(entry); err != nil {
+				slog.ErrorContext(r.Context(), "failed to write audit entry", "error", err)
+			}
+		})
+	}
+}
+
+// auditTarget describes what a request acted on from its route parameters, e.g. "ref=default id=avatars".
+func auditTarget(rctx *chi.Context) string {
+	var parts []string
+	for i, key := range rctx.URLParams.Keys {
+		if key == "*" || i >= len(rctx.URLParams.Values) {
+			continue
+		}
+		parts = append(parts, key+"="+rctx.URLParams.Values[i])
+	}
+	return strings.Join(parts, " ")
+}
+
+func (api *API) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, "GET")
+		return
+	}
+	if api.audit == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]any{"message": "Audit log is not enabled"}})
+		return
+	}
+
+	query := r.URL.Query()
+	filter := audit.Filter{
+		User:    query.Get("user"),
+		Method:  query.Get("method"),
+		Outcome: query.Get("outcome"),
+		Target:  query.Get("target"),
+	}
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(query.Get("offset"))
+	for key, dest := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := query.Get(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"message": "Invalid " + key + " timestamp"}})
+			return
+		}
+		*dest = parsed
+	}
+
+	page, err := api.audit.Query(filter)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": err.Error()}})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
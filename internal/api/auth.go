@@ -7,19 +7,21 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
 )
 
-func (api *API) authBaseURL() string {
-	return strings.TrimSuffix(api.cfg.SupabaseURL, "/") + "/auth/v1"
+func authBaseURL(project config.Project) string {
+	return strings.TrimSuffix(project.SupabaseURL, "/") + "/auth/v1"
 }
 
-func (api *API) authHeaders() http.Header {
+func authHeaders(project config.Project) http.Header {
 	headers := http.Header{}
 	headers.Set("Content-Type", "application/json")
 	headers.Set("Accept", "application/json")
-	if api.cfg.SupabaseServiceKey != "" {
-		headers.Set("Authorization", "Bearer "+api.cfg.SupabaseServiceKey)
-		headers.Set("apikey", api.cfg.SupabaseServiceKey)
+	if serviceKey := project.SupabaseServiceKey; serviceKey != "" {
+		headers.Set("Authorization", "Bearer "+serviceKey)
+		headers.Set("apikey", serviceKey)
 	}
 	return headers
 }
@@ -83,14 +85,16 @@ func (api *API) handleAuthUserFactors(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) authProxy(w http.ResponseWriter, r *http.Request, method, path string, body []byte) {
-	if strings.TrimSpace(api.cfg.SupabaseServiceKey) == "" {
+	project := api.project(r)
+	serviceKey := strings.TrimSpace(project.SupabaseServiceKey)
+	if serviceKey == "" {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"message": "Missing service key. Set SUPABASE_SERVICE_KEY (or SUPABASE_SERVICE_ROLE_KEY / SERVICE_ROLE_KEY / SERVICE_KEY).",
 		})
 		return
 	}
 
-	target := api.authBaseURL() + path
+	target := authBaseURL(project) + path
 	resp, respBody, err := api.doAuthRequest(r, method, target, body)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
@@ -100,8 +104,8 @@ func (api *API) authProxy(w http.ResponseWriter, r *http.Request, method, path s
 
 	// Some proxies strip custom headers before forwarding to Kong/Gotrue.
 	// Retry once with `apikey` as query parameter to mirror key-auth query mode.
-	if isNoAPIKeyResponse(resp.StatusCode, respBody) && strings.TrimSpace(api.cfg.SupabaseServiceKey) != "" {
-		retryTarget := withAPIKeyQuery(target, api.cfg.SupabaseServiceKey)
+	if isNoAPIKeyResponse(resp.StatusCode, respBody) {
+		retryTarget := withAPIKeyQuery(target, serviceKey)
 		retryResp, retryBody, retryErr := api.doAuthRequest(r, method, retryTarget, body)
 		if retryErr == nil {
 			resp.Body.Close()
@@ -151,18 +155,23 @@ func (api *API) doAuthRequest(r *http.Request, method, target string, body []byt
 	if err != nil {
 		return nil, nil, err
 	}
-	req.Header = api.authHeaders()
+	req.Header = authHeaders(api.project(r))
 
 	resp, err := api.client.Do(req)
 	if err != nil {
+		api.logUpstreamFailure(req, 0, nil, err)
 		return nil, nil, err
 	}
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		resp.Body.Close()
+		api.logUpstreamFailure(req, 0, nil, err)
 		return nil, nil, err
 	}
+	if resp.StatusCode >= 400 {
+		api.logUpstreamFailure(req, resp.StatusCode, respBody, nil)
+	}
 	return resp, respBody, nil
 }
 
@@ -11,13 +11,7 @@ import (
 )
 
 func TestAuthHeadersIncludeAPIKeyAndBearerToken(t *testing.T) {
-	api := &API{
-		cfg: config.Config{
-			SupabaseServiceKey: "service-role",
-		},
-	}
-
-	headers := api.authHeaders()
+	headers := authHeaders(config.Project{SupabaseServiceKey: "service-role"})
 
 	if got := headers.Get("apikey"); got != "service-role" {
 		t.Fatalf("expected apikey header, got %q", got)
@@ -28,9 +22,7 @@ func TestAuthHeadersIncludeAPIKeyAndBearerToken(t *testing.T) {
 }
 
 func TestAuthHeadersOmitAuthWhenServiceKeyMissing(t *testing.T) {
-	api := &API{}
-
-	headers := api.authHeaders()
+	headers := authHeaders(config.Project{})
 
 	if got := headers.Get("apikey"); got != "" {
 		t.Fatalf("expected empty apikey header when key missing, got %q", got)
@@ -59,10 +51,10 @@ func TestAuthProxyRetriesWithAPIKeyQueryWhenHeaderNotDetected(t *testing.T) {
 	defer srv.Close()
 
 	api := &API{
-		cfg: config.Config{
+		holder: config.NewHolder(config.Config{
 			SupabaseURL:        srv.URL,
 			SupabaseServiceKey: "service-role",
-		},
+		}, ""),
 		client: srv.Client(),
 	}
 
@@ -84,9 +76,9 @@ func TestAuthProxyRetriesWithAPIKeyQueryWhenHeaderNotDetected(t *testing.T) {
 
 func TestAuthProxyReturnsConfigErrorWhenServiceKeyMissing(t *testing.T) {
 	api := &API{
-		cfg: config.Config{
+		holder: config.NewHolder(config.Config{
 			SupabaseURL: "http://localhost:9999",
-		},
+		}, ""),
 	}
 
 	req := httptest.NewRequest(http.MethodPost, "/api/platform/auth/default/users", strings.NewReader(`{}`))
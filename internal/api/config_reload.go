@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+// handleConfigReload does the same as sending SIGHUP: it re-reads the config file and swaps
+// the new configuration in for subsequent requests. Changes to settings that are only read at
+// startup are listed separately under restart_required.
+func (api *API) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, "POST")
+		return
+	}
+
+	changes, issues, err := api.holder.Reload()
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		messages = append(messages, issue.String())
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error":  map[string]any{"message": err.Error()},
+			"issues": messages,
+		})
+		return
+	}
+
+	applied, restart := []config.Change{}, []config.Change{}
+	for _, change := range changes {
+		if change.RestartRequired {
+			restart = append(restart, change)
+		} else {
+			applied = append(applied, change)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"changes": applied, "restart_required": restart, "issues": messages})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+func TestConfigReloadEndpointAppliesToLaterRequests(t *testing.T) {
+	t.Setenv("DEFAULT_ORGANIZATION_NAME", "")
+	t.Setenv("SUPABASE_STUDIO_GO_LISTEN", "")
+	t.Setenv("STUDIO_GO_LISTEN", "")
+	path := filepath.Join(t.TempDir(), "studio.yaml")
+	if err := os.WriteFile(path, []byte("default_organization_name: Acme\n"), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	cfg, _, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	handler := newTestRouter(config.NewHolder(cfg, path))
+
+	organizationName := func() string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/platform/organizations", nil))
+		return rec.Body.String()
+	}
+	if body := organizationName(); !strings.Contains(body, `"name":"Acme"`) {
+		t.Fatalf("expected initial organization name, got %s", body)
+	}
+
+	if err := os.WriteFile(path, []byte("default_organization_name: Globex\nsupabase_studio_go_listen: :9000\n"), 0o600); err != nil {
+		t.Fatalf("failed to rewrite config file: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, withRole(httptest.NewRequest(http.MethodPost, "/platform/config/reload", nil), auth.RoleDeveloper))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected developers to be refused, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withRole(httptest.NewRequest(http.MethodPost, "/platform/config/reload", nil), auth.RoleAdmin))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"key":"DefaultOrganizationName"`) {
+		t.Fatalf("expected reload to report the change, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"restart_required":[{"key":"ListenAddress","old":"","new":":9000","restart_required":true}]`) {
+		t.Fatalf("expected the listen address to need a restart, got %s", rec.Body.String())
+	}
+	if body := organizationName(); !strings.Contains(body, `"name":"Globex"`) {
+		t.Fatalf("expected reloaded organization name, got %s", body)
+	}
+}
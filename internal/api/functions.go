@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Gouryella/supabase-studio-go/internal/config"
 	"github.com/google/uuid"
 )
 
@@ -17,8 +18,8 @@ type functionArtifact struct {
 	UpdatedAt     int64
 }
 
-func (api *API) listFunctions() ([]map[string]any, error) {
-	artifacts, err := api.loadFunctionArtifacts()
+func (api *API) listFunctions(project config.Project) ([]map[string]any, error) {
+	artifacts, err := api.loadFunctionArtifacts(project)
 	if err != nil {
 		return nil, err
 	}
@@ -42,8 +43,8 @@ func (api *API) listFunctions() ([]map[string]any, error) {
 	return response, nil
 }
 
-func (api *API) getFunctionBySlug(slug string) (map[string]any, error) {
-	artifacts, err := api.loadFunctionArtifacts()
+func (api *API) getFunctionBySlug(project config.Project, slug string) (map[string]any, error) {
+	artifacts, err := api.loadFunctionArtifacts(project)
 	if err != nil {
 		return nil, err
 	}
@@ -64,8 +65,8 @@ func (api *API) getFunctionBySlug(slug string) (map[string]any, error) {
 	return nil, errors.New("not found")
 }
 
-func (api *API) loadFunctionArtifacts() ([]functionArtifact, error) {
-	for _, folder := range api.functionFolderCandidates() {
+func (api *API) loadFunctionArtifacts(project config.Project) ([]functionArtifact, error) {
+	for _, folder := range api.functionFolderCandidates(project) {
 		artifacts, found, err := loadFunctionArtifactsFromFolder(folder)
 		if err != nil {
 			return nil, err
@@ -77,12 +78,16 @@ func (api *API) loadFunctionArtifacts() ([]functionArtifact, error) {
 	return []functionArtifact{}, nil
 }
 
-func (api *API) functionFolderCandidates() []string {
+// functionFolderCandidates lists the folders to read functions from. The supabase/functions
+// folder of the working directory only stands in for the default project's.
+func (api *API) functionFolderCandidates(project config.Project) []string {
 	var folders []string
-	if configured := strings.TrimSpace(api.cfg.EdgeFunctionsFolder); configured != "" {
+	if configured := strings.TrimSpace(project.EdgeFunctionsFolder); configured != "" {
 		folders = append(folders, configured)
 	}
-	folders = append(folders, filepath.Join("supabase", "functions"))
+	if project.Ref == config.DefaultProjectRef {
+		folders = append(folders, filepath.Join("supabase", "functions"))
+	}
 	return folders
 }
 
@@ -22,12 +22,13 @@ func TestListFunctionsReturnsEmptyWhenFolderMissing(t *testing.T) {
 	})
 
 	api := &API{
-		cfg: config.Config{
+		holder: config.NewHolder(config.Config{
 			EdgeFunctionsFolder: filepath.Join(tmpDir, "does-not-exist"),
-		},
+		}, ""),
 	}
 
-	functions, err := api.listFunctions()
+	project, _ := api.config().Project(config.DefaultProjectRef)
+	functions, err := api.listFunctions(project)
 	if err != nil {
 		t.Fatalf("expected missing function folder to be non-fatal, got: %v", err)
 	}
@@ -57,9 +58,10 @@ func TestListFunctionsFallsBackToSupabaseFunctionsFolder(t *testing.T) {
 		t.Fatalf("failed to write function entrypoint: %v", err)
 	}
 
-	api := &API{}
+	api := &API{holder: config.NewHolder(config.Config{}, "")}
 
-	functions, err := api.listFunctions()
+	project, _ := api.config().Project(config.DefaultProjectRef)
+	functions, err := api.listFunctions(project)
 	if err != nil {
 		t.Fatalf("expected fallback function directory to be readable, got: %v", err)
 	}
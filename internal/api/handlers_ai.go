@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/metrics"
+	"github.com/Gouryella/supabase-studio-go/internal/ratelimit"
 )
 
 type aiPolicyRequest struct {
@@ -408,6 +410,7 @@ func (api *API) handleAIDocs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no")
 	w.WriteHeader(http.StatusOK)
+	defer metrics.TrackStream(r)()
 
 	chunks := splitTextChunks(answer, 220)
 	now := time.Now().Unix()
@@ -481,6 +484,7 @@ func (api *API) handleAIOnboardingDesign(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("X-Vercel-AI-UI-Message-Stream", "v1")
 	w.Header().Set("X-Accel-Buffering", "no")
 	w.WriteHeader(http.StatusOK)
+	defer metrics.TrackStream(r)()
 
 	_ = writeSSEChunk(w, flusher, map[string]any{"type": "start"})
 
@@ -565,12 +569,13 @@ func (api *API) handleAIOnboardingDesign(w http.ResponseWriter, r *http.Request)
 }
 
 func (api *API) generateOpenAIText(ctx context.Context, requestedModel string, messages []openAIChatMessage) (string, string, int, string) {
-	apiKey := strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+	cfg := api.config()
+	apiKey := strings.TrimSpace(cfg.OpenAIAPIKey)
 	if apiKey == "" {
 		return "", "", http.StatusBadRequest, "OPENAI_API_KEY is not configured"
 	}
 
-	model := pickAIModel(requestedModel, parseOpenAIModelsEnv())
+	model := pickAIModel(requestedModel, parseOpenAIModels(cfg.OpenAIModels))
 	if model == "" {
 		return "", "", http.StatusBadRequest, "No AI model configured. Set OPENAI_MODELS or OPENAI_MODEL."
 	}
@@ -585,7 +590,7 @@ func (api *API) generateOpenAIText(ctx context.Context, requestedModel string, m
 	}
 	bodyBytes, _ := json.Marshal(requestBody)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resolveOpenAIChatCompletionsURL(), bytes.NewReader(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resolveOpenAIChatCompletionsURL(cfg.OpenAIAPIURL), bytes.NewReader(bodyBytes))
 	if err != nil {
 		return "", model, http.StatusInternalServerError, "Failed to create upstream request"
 	}
@@ -594,12 +599,14 @@ func (api *API) generateOpenAIText(ctx context.Context, requestedModel string, m
 
 	resp, err := api.client.Do(req)
 	if err != nil {
+		api.logUpstreamFailure(req, 0, nil, err)
 		return "", model, http.StatusBadGateway, fmt.Sprintf("Upstream AI request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	respBytes, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode >= 400 {
+		api.logUpstreamFailure(req, resp.StatusCode, respBytes, nil)
 		return "", model, resp.StatusCode, parseUpstreamAIError(respBytes)
 	}
 
@@ -608,10 +615,29 @@ func (api *API) generateOpenAIText(ctx context.Context, requestedModel string, m
 		return "", model, http.StatusBadGateway, "Failed to parse upstream AI response"
 	}
 	if len(completion.Choices) == 0 {
+		api.spendAITokens(ctx, completion.Usage, len(bodyBytes))
 		return "", model, http.StatusBadGateway, "Upstream AI response did not contain any choices"
 	}
 
-	return strings.TrimSpace(extractOpenAIContentText(completion.Choices[0].Message.Content)), model, 0, ""
+	text := extractOpenAIContentText(completion.Choices[0].Message.Content)
+	api.spendAITokens(ctx, completion.Usage, len(bodyBytes)+len(text))
+	return strings.TrimSpace(text), model, 0, ""
+}
+
+// spendAITokens charges usage to the client's daily AI budget. When the upstream did not
+// report it, as for a stream that ended early because the client went away or the server
+// is draining, it charges an estimate for the given bytes of prompt and generated text.
+func (api *API) spendAITokens(ctx context.Context, usage *openAIUsage, textBytes int) {
+	tokens := estimateTokens(textBytes)
+	if usage != nil {
+		tokens = usage.TotalTokens
+	}
+	api.aiBudget.Spend(ratelimit.KeyFromContext(ctx), tokens)
+}
+
+// estimateTokens approximates the tokens in n bytes of text, at about four bytes per token.
+func estimateTokens(n int) int64 {
+	return int64((n + 3) / 4)
 }
 
 func parseUpstreamAIError(respBytes []byte) string {
@@ -3,6 +3,7 @@ package api
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,11 +14,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Gouryella/supabase-studio-go/internal/lifecycle"
+	"github.com/Gouryella/supabase-studio-go/internal/metrics"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 func (api *API) handleIncidentStatus(w http.ResponseWriter, r *http.Request) {
-	if !api.cfg.IsPlatform {
+	if !api.config().IsPlatform {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
@@ -34,8 +37,9 @@ func (api *API) handleIncidentStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pageID := os.Getenv("STATUSPAGE_PAGE_ID")
-	apiKey := os.Getenv("STATUSPAGE_API_KEY")
+	cfg := api.config()
+	pageID := cfg.StatusPageID
+	apiKey := cfg.StatusPageAPIKey
 	if pageID == "" || apiKey == "" {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "StatusPage not configured"})
 		return
@@ -242,7 +246,7 @@ func (api *API) handleGenerateAttachmentURL(w http.ResponseWriter, r *http.Reque
 		payload.Bucket = "support-attachments"
 	}
 
-	sub, err := extractJWTSubject(token, api.cfg.AuthJWTSecret)
+	sub, err := extractJWTSubject(token, api.project(r).AuthJWTSecret)
 	if err != nil {
 		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": map[string]any{"message": "Unauthorized"}})
 		return
@@ -262,12 +266,14 @@ func (api *API) handleGenerateAttachmentURL(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	if api.cfg.SupportAPIURL == "" || api.cfg.SupportAPIKey == "" {
+	cfg := api.config()
+	supportAPIKey := cfg.SupportAPIKey
+	if cfg.SupportAPIURL == "" || supportAPIKey == "" {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": "Support API is not configured"}})
 		return
 	}
 
-	urlStr := strings.TrimSuffix(api.cfg.SupportAPIURL, "/") + "/storage/v1/object/sign/" + payload.Bucket
+	urlStr := strings.TrimSuffix(cfg.SupportAPIURL, "/") + "/storage/v1/object/sign/" + payload.Bucket
 	body, _ := json.Marshal(map[string]any{
 		"paths":     payload.Filenames,
 		"expiresIn": 10 * 365 * 24 * 60 * 60,
@@ -278,8 +284,8 @@ func (api *API) handleGenerateAttachmentURL(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("apikey", api.cfg.SupportAPIKey)
-	req.Header.Set("Authorization", "Bearer "+api.cfg.SupportAPIKey)
+	req.Header.Set("apikey", supportAPIKey)
+	req.Header.Set("Authorization", "Bearer "+supportAPIKey)
 
 	resp, err := api.client.Do(req)
 	if err != nil {
@@ -333,11 +339,8 @@ func (api *API) handleMCP(w http.ResponseWriter, r *http.Request) {
 	respondNotImplemented(w, "MCP endpoint is not available in the Go runtime")
 }
 
-func parseOpenAIModelsEnv() []string {
-	raw := strings.TrimSpace(os.Getenv("OPENAI_MODELS"))
-	if raw == "" {
-		raw = strings.TrimSpace(os.Getenv("OPENAI_MODEL"))
-	}
+func parseOpenAIModels(raw string) []string {
+	raw = strings.TrimSpace(raw)
 	if raw == "" {
 		return nil
 	}
@@ -384,14 +387,15 @@ func normalizeModelList(models []string) []string {
 }
 
 func (api *API) handleCheckAPIKey(w http.ResponseWriter, r *http.Request) {
-	models := parseOpenAIModelsEnv()
+	cfg := api.config()
+	models := parseOpenAIModels(cfg.OpenAIModels)
 	defaultModel := ""
 	if len(models) > 0 {
 		defaultModel = models[0]
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
-		"hasKey":       os.Getenv("OPENAI_API_KEY") != "",
+		"hasKey":       cfg.OpenAIAPIKey != "",
 		"models":       models,
 		"defaultModel": defaultModel,
 	})
@@ -419,9 +423,18 @@ type openAIChatMessage struct {
 }
 
 type openAIChatRequest struct {
-	Model    string              `json:"model"`
-	Messages []openAIChatMessage `json:"messages"`
-	Stream   bool                `json:"stream"`
+	Model         string               `json:"model"`
+	Messages      []openAIChatMessage  `json:"messages"`
+	Stream        bool                 `json:"stream"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	TotalTokens int64 `json:"total_tokens"`
 }
 
 type openAIChatResponse struct {
@@ -430,6 +443,7 @@ type openAIChatResponse struct {
 			Content any `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
@@ -446,6 +460,7 @@ type openAIChatStreamResponse struct {
 			Content any `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
 }
 
 func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
@@ -454,7 +469,8 @@ func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apiKey := strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+	cfg := api.config()
+	apiKey := strings.TrimSpace(cfg.OpenAIAPIKey)
 	if apiKey == "" {
 		writeJSON(w, http.StatusBadRequest, map[string]any{
 			"error": "OPENAI_API_KEY is not configured",
@@ -470,8 +486,7 @@ func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	models := parseOpenAIModelsEnv()
-	model := pickAIModel(payload.Model, models)
+	model := pickAIModel(payload.Model, parseOpenAIModels(cfg.OpenAIModels))
 	if model == "" {
 		writeJSON(w, http.StatusBadRequest, map[string]any{
 			"error": "No AI model configured. Set OPENAI_MODELS or OPENAI_MODEL.",
@@ -492,10 +507,18 @@ func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
 		Messages: openAIMessages,
 		Stream:   true,
 	}
+	if api.aiBudget != nil {
+		requestBody.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
 	bodyBytes, _ := json.Marshal(requestBody)
 
-	urlStr := resolveOpenAIChatCompletionsURL()
-	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, urlStr, bytes.NewReader(bodyBytes))
+	// On shutdown, stop reading from the model early so the stream still ends with a finish event.
+	upstreamCtx, cancelUpstream := context.WithCancel(r.Context())
+	defer cancelUpstream()
+	defer lifecycle.AfterStop(r.Context(), cancelUpstream)()
+
+	urlStr := resolveOpenAIChatCompletionsURL(cfg.OpenAIAPIURL)
+	req, err := http.NewRequestWithContext(upstreamCtx, http.MethodPost, urlStr, bytes.NewReader(bodyBytes))
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"error": "Failed to create upstream request",
@@ -507,6 +530,7 @@ func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := api.client.Do(req)
 	if err != nil {
+		api.logUpstreamFailure(req, 0, nil, err)
 		writeJSON(w, http.StatusBadGateway, map[string]any{
 			"error": fmt.Sprintf("Upstream AI request failed: %v", err),
 		})
@@ -516,6 +540,7 @@ func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
 
 	if resp.StatusCode >= 400 {
 		respBytes, _ := io.ReadAll(resp.Body)
+		api.logUpstreamFailure(req, resp.StatusCode, respBytes, nil)
 		msg := strings.TrimSpace(string(respBytes))
 		var upstreamErr openAIChatResponse
 		if err := json.Unmarshal(respBytes, &upstreamErr); err == nil && upstreamErr.Error != nil && upstreamErr.Error.Message != "" {
@@ -544,6 +569,7 @@ func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("X-Vercel-AI-UI-Message-Stream", "v1")
 	w.Header().Set("X-Accel-Buffering", "no")
 	w.WriteHeader(http.StatusOK)
+	defer metrics.TrackStream(r)()
 
 	const textID = "text-1"
 	_ = writeSSEChunk(w, flusher, map[string]any{"type": "start"})
@@ -553,10 +579,12 @@ func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
 	wroteDelta := false
 
 	if strings.Contains(contentType, "text/event-stream") {
-		_ = streamOpenAIResponse(resp.Body, func(delta string) error {
+		streamed := 0
+		usage, _ := streamOpenAIResponse(resp.Body, func(delta string) error {
 			if delta == "" {
 				return nil
 			}
+			streamed += len(delta)
 			for _, piece := range splitStreamingText(delta) {
 				wroteDelta = true
 				if err := writeSSEChunk(w, flusher, map[string]any{"type": "text-delta", "id": textID, "delta": piece}); err != nil {
@@ -565,15 +593,19 @@ func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
 			}
 			return nil
 		})
+		api.spendAITokens(r.Context(), usage, len(bodyBytes)+streamed)
 	} else {
 		respBytes, _ := io.ReadAll(resp.Body)
 		var completion openAIChatResponse
-		if err := json.Unmarshal(respBytes, &completion); err == nil && len(completion.Choices) > 0 {
-			answer := extractOpenAIContentText(completion.Choices[0].Message.Content)
-			if answer != "" {
-				wroteDelta = true
-				_ = writeSSEChunk(w, flusher, map[string]any{"type": "text-delta", "id": textID, "delta": answer})
-			}
+		_ = json.Unmarshal(respBytes, &completion)
+		answer := ""
+		if len(completion.Choices) > 0 {
+			answer = extractOpenAIContentText(completion.Choices[0].Message.Content)
+		}
+		api.spendAITokens(r.Context(), completion.Usage, len(bodyBytes)+len(answer))
+		if answer != "" {
+			wroteDelta = true
+			_ = writeSSEChunk(w, flusher, map[string]any{"type": "text-delta", "id": textID, "delta": answer})
 		}
 	}
 
@@ -591,7 +623,10 @@ func (api *API) handleAISQLGenerateV4(w http.ResponseWriter, r *http.Request) {
 	flusher.Flush()
 }
 
-func streamOpenAIResponse(body io.Reader, onDelta func(string) error) error {
+// streamOpenAIResponse passes each text delta to onDelta and returns the token usage if the
+// upstream reported it.
+func streamOpenAIResponse(body io.Reader, onDelta func(string) error) (*openAIUsage, error) {
+	var usage *openAIUsage
 	scanner := bufio.NewScanner(body)
 	scanner.Buffer(make([]byte, 0, 64*1024), 2*1024*1024)
 
@@ -609,13 +644,16 @@ func streamOpenAIResponse(body io.Reader, onDelta func(string) error) error {
 			continue
 		}
 		if payload == "[DONE]" {
-			return nil
+			return usage, nil
 		}
 
 		var chunk openAIChatStreamResponse
 		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
 			continue
 		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
 
 		for _, choice := range chunk.Choices {
 			delta := extractOpenAIContentText(choice.Delta.Content)
@@ -626,12 +664,12 @@ func streamOpenAIResponse(body io.Reader, onDelta func(string) error) error {
 				continue
 			}
 			if err := onDelta(delta); err != nil {
-				return err
+				return usage, err
 			}
 		}
 	}
 
-	return scanner.Err()
+	return usage, scanner.Err()
 }
 
 func splitStreamingText(text string) []string {
@@ -646,8 +684,8 @@ func splitStreamingText(text string) []string {
 	return out
 }
 
-func resolveOpenAIChatCompletionsURL() string {
-	raw := strings.TrimSpace(os.Getenv("OPENAI_API_URL"))
+func resolveOpenAIChatCompletionsURL(raw string) string {
+	raw = strings.TrimSpace(raw)
 	if raw == "" {
 		return "https://api.openai.com/v1/chat/completions"
 	}
@@ -670,10 +708,6 @@ func pickAIModel(requested string, configured []string) string {
 		return configured[0]
 	}
 
-	if fallback := strings.TrimSpace(os.Getenv("OPENAI_MODEL")); fallback != "" {
-		return fallback
-	}
-
 	return ""
 }
 
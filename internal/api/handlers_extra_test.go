@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/lifecycle"
+)
+
+func TestGenerateV4FinishesStreamOnShutdown(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"select\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	handler := newTestRouter(config.NewHolder(config.Config{
+		OpenAIAPIKey: "test-key",
+		OpenAIAPIURL: upstream.URL,
+		OpenAIModels: "test-model",
+	}, ""))
+
+	drain := lifecycle.NewDrain()
+	req := httptest.NewRequest(http.MethodPost, "/ai/sql/generate-v4", strings.NewReader(`{"messages":[{"role":"user","content":"list tables"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(lifecycle.WithDrain(req.Context(), drain))
+	rec := httptest.NewRecorder()
+
+	time.AfterFunc(200*time.Millisecond, drain.Stop)
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not end after drain stop")
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"delta":"s"`) {
+		t.Fatalf("expected streamed delta before shutdown, got %s", body)
+	}
+	if !strings.Contains(body, `{"type":"finish"}`) || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Fatalf("expected finish event, got %s", body)
+	}
+}
+
+func TestAIRoutesChargeDailyTokenBudget(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"title\":\"Users\",\"description\":\"List users\"}"}}],"usage":{"total_tokens":150}}`))
+	}))
+	defer upstream.Close()
+
+	handler := newTestRouter(config.NewHolder(config.Config{
+		OpenAIAPIKey:  "test-key",
+		OpenAIAPIURL:  upstream.URL,
+		OpenAIModels:  "test-model",
+		RateLimitAI:   10,
+		AIDailyTokens: 100,
+	}, ""))
+
+	codes := make([]int, 0, 2)
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/ai/sql/title-v2", strings.NewReader(`{"sql":"select * from users"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("expected the request after spending the budget to be refused, got %v", codes)
+	}
+}
+
+func TestAIStreamsWithoutUsageChargeAnEstimate(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"" + strings.Repeat("select ", 20) + "\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	handler := newTestRouter(config.NewHolder(config.Config{
+		OpenAIAPIKey:  "test-key",
+		OpenAIAPIURL:  upstream.URL,
+		OpenAIModels:  "test-model",
+		AIDailyTokens: 50,
+	}, ""))
+
+	generate := func() int {
+		drain := lifecycle.NewDrain()
+		req := httptest.NewRequest(http.MethodPost, "/ai/sql/generate-v4", strings.NewReader(`{"messages":[{"role":"user","content":"list tables"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(lifecycle.WithDrain(req.Context(), drain))
+		time.AfterFunc(100*time.Millisecond, drain.Stop)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := generate(); code != http.StatusOK {
+		t.Fatalf("expected the first stream to run, got %d", code)
+	}
+	if code := generate(); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the estimate of the drained stream to spend the budget, got %d", code)
+	}
+}
@@ -7,9 +7,12 @@ import (
 )
 
 func (api *API) ensureManagedFolders() error {
-	folders := []string{
-		strings.TrimSpace(api.cfg.EdgeFunctionsFolder),
-		strings.TrimSpace(api.cfg.SnippetsFolder),
+	var folders []string
+	for _, project := range api.config().AllProjects() {
+		folders = append(folders,
+			strings.TrimSpace(project.EdgeFunctionsFolder),
+			strings.TrimSpace(project.SnippetsFolder),
+		)
 	}
 
 	for _, folder := range folders {
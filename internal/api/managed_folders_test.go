@@ -14,10 +14,10 @@ func TestEnsureManagedFoldersCreatesConfiguredDirectories(t *testing.T) {
 	snippetsDir := filepath.Join(tmpDir, "snippets")
 
 	api := &API{
-		cfg: config.Config{
+		holder: config.NewHolder(config.Config{
 			EdgeFunctionsFolder: edgeDir,
 			SnippetsFolder:      snippetsDir,
-		},
+		}, ""),
 	}
 
 	if err := api.ensureManagedFolders(); err != nil {
@@ -33,7 +33,7 @@ func TestEnsureManagedFoldersCreatesConfiguredDirectories(t *testing.T) {
 }
 
 func TestEnsureManagedFoldersSkipsEmptyValues(t *testing.T) {
-	api := &API{}
+	api := &API{holder: config.NewHolder(config.Config{}, "")}
 	if err := api.ensureManagedFolders(); err != nil {
 		t.Fatalf("expected empty folder config to be ignored, got: %v", err)
 	}
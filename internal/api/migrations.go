@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"net/http"
 	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/audit"
 )
 
 func (api *API) handleMigrations(w http.ResponseWriter, r *http.Request) {
@@ -47,6 +49,8 @@ func (api *API) handleApplyMigration(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"message": "Invalid request body", "formattedError": "Invalid request body"})
 		return
 	}
+	audit.Annotate(r.Context(), "migration", payload.Name)
+	audit.Annotate(r.Context(), "query", audit.RedactSQL(payload.Query))
 
 	initQuery := `begin;
 
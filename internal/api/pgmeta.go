@@ -14,6 +14,10 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/audit"
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/config"
 )
 
 type pgMetaError struct {
@@ -24,7 +28,8 @@ type pgMetaError struct {
 
 func (api *API) pgMetaProxy(endpoint string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if api.cfg.StudioPgMetaURL == "" {
+		project := api.project(r)
+		if project.StudioPgMetaURL == "" {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{
 				"message": "STUDIO_PG_META_URL is required",
 			})
@@ -32,7 +37,7 @@ func (api *API) pgMetaProxy(endpoint string) http.HandlerFunc {
 		}
 
 		query := r.URL.RawQuery
-		target := fmt.Sprintf("%s/%s", strings.TrimSuffix(api.cfg.StudioPgMetaURL, "/"), endpoint)
+		target := fmt.Sprintf("%s/%s", strings.TrimSuffix(project.StudioPgMetaURL, "/"), endpoint)
 		if query != "" {
 			target = target + "?" + query
 		}
@@ -71,7 +76,9 @@ func (api *API) pgMetaProxy(endpoint string) http.HandlerFunc {
 }
 
 func (api *API) handlePgMetaQuery(w http.ResponseWriter, r *http.Request) {
-	if api.cfg.StudioPgMetaURL == "" {
+	project := api.project(r)
+	direct := api.config().PostgresDirect
+	if project.StudioPgMetaURL == "" && !direct {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"message": "STUDIO_PG_META_URL is required",
 		})
@@ -88,6 +95,25 @@ func (api *API) handlePgMetaQuery(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	audit.Annotate(r.Context(), "query", audit.RedactSQL(payload.Query))
+
+	if direct {
+		body, pgErr, status, err := api.postgresExecute(r, payload.Query, false)
+		switch {
+		case err != nil:
+			writeJSON(w, status, map[string]any{"message": err.Error()})
+		case pgErr != nil:
+			writeJSON(w, status, map[string]any{
+				"message":        pgErr.Message,
+				"formattedError": pgErr.FormattedError,
+			})
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write(body)
+		}
+		return
+	}
 
 	headers, err := api.pgMetaHeaders(r, false)
 	if err != nil {
@@ -99,7 +125,7 @@ func (api *API) handlePgMetaQuery(w http.ResponseWriter, r *http.Request) {
 		"query": payload.Query,
 	})
 
-	target := fmt.Sprintf("%s/query", strings.TrimSuffix(api.cfg.StudioPgMetaURL, "/"))
+	target := fmt.Sprintf("%s/query", strings.TrimSuffix(project.StudioPgMetaURL, "/"))
 	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, target, bytes.NewReader(body))
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
@@ -138,12 +164,15 @@ func (api *API) handlePgMetaQuery(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) pgMetaExecute(r *http.Request, query string, readOnly bool) ([]byte, *pgMetaError, int, error) {
+	if api.config().PostgresDirect {
+		return api.postgresExecute(r, query, readOnly)
+	}
 	headers, err := api.pgMetaHeaders(r, readOnly)
 	if err != nil {
 		return nil, nil, http.StatusInternalServerError, err
 	}
 	body, _ := json.Marshal(map[string]any{"query": query})
-	target := fmt.Sprintf("%s/query", strings.TrimSuffix(api.cfg.StudioPgMetaURL, "/"))
+	target := fmt.Sprintf("%s/query", strings.TrimSuffix(api.project(r).StudioPgMetaURL, "/"))
 	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, target, bytes.NewReader(body))
 	if err != nil {
 		return nil, nil, http.StatusInternalServerError, err
@@ -151,11 +180,13 @@ func (api *API) pgMetaExecute(r *http.Request, query string, readOnly bool) ([]b
 	req.Header = headers
 	resp, err := api.client.Do(req)
 	if err != nil {
+		api.logUpstreamFailure(req, 0, nil, err)
 		return nil, nil, http.StatusInternalServerError, err
 	}
 	defer resp.Body.Close()
 	respBody, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode >= 400 {
+		api.logUpstreamFailure(req, resp.StatusCode, respBody, nil)
 		var pgErr pgMetaError
 		if err := json.Unmarshal(respBody, &pgErr); err == nil && pgErr.Message != "" {
 			return respBody, &pgErr, resp.StatusCode, nil
@@ -166,44 +197,62 @@ func (api *API) pgMetaExecute(r *http.Request, query string, readOnly bool) ([]b
 }
 
 func (api *API) pgMetaHeaders(r *http.Request, readOnly bool) (http.Header, error) {
+	readOnly = queryReadOnly(r, readOnly)
+
 	headers := http.Header{}
 	headers.Set("Accept", "application/json")
 	headers.Set("Content-Type", "application/json")
-	if auth := r.Header.Get("Authorization"); auth != "" {
-		headers.Set("Authorization", auth)
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		headers.Set("Authorization", authorization)
 	}
-	if cookie := r.Header.Get("cookie"); cookie != "" {
+	if cookie := upstreamCookies(r.Header.Get("cookie")); cookie != "" {
 		headers.Set("cookie", cookie)
 	}
 
-	connectionString := api.pgMetaConnectionString(readOnly)
-	encrypted, err := encryptString(connectionString, api.cfg.PgMetaCryptoKey)
+	project := api.project(r)
+	connectionString := pgMetaConnectionString(project, readOnly)
+	encrypted, err := encryptString(connectionString, project.PgMetaCryptoKey)
 	if err != nil {
 		return nil, err
 	}
 	headers.Set("x-connection-encrypted", encrypted)
 
-	if api.cfg.SupabaseServiceKey != "" {
-		headers.Set("apiKey", api.cfg.SupabaseServiceKey)
+	if serviceKey := project.SupabaseServiceKey; serviceKey != "" {
+		headers.Set("apiKey", serviceKey)
 	}
 
 	return headers, nil
 }
 
-func (api *API) pgMetaConnectionString(readOnly bool) string {
-	user := api.cfg.PostgresUserReadWrite
+func pgMetaConnectionString(project config.Project, readOnly bool) string {
+	user := project.PostgresUserReadWrite
 	if readOnly {
-		user = api.cfg.PostgresUserReadOnly
+		user = project.PostgresUserReadOnly
 	}
 	return fmt.Sprintf("postgresql://%s:%s@%s:%s/%s",
 		user,
-		api.cfg.PostgresPassword,
-		api.cfg.PostgresHost,
-		api.cfg.PostgresPort,
-		api.cfg.PostgresDatabase,
+		project.PostgresPassword,
+		project.PostgresHost,
+		project.PostgresPort,
+		project.PostgresDatabase,
 	)
 }
 
+// upstreamCookies drops the dashboard's own session cookies from a Cookie header, so that
+// upstreams never see them.
+func upstreamCookies(header string) string {
+	var kept []string
+	for _, cookie := range strings.Split(header, ";") {
+		cookie = strings.TrimSpace(cookie)
+		name, _, _ := strings.Cut(cookie, "=")
+		if cookie == "" || auth.IsSessionCookie(name) {
+			continue
+		}
+		kept = append(kept, cookie)
+	}
+	return strings.Join(kept, "; ")
+}
+
 // encryptString matches CryptoJS AES encryption with passphrase (OpenSSL compatible).
 func encryptString(value, passphrase string) (string, error) {
 	if passphrase == "" {
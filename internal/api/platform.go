@@ -2,6 +2,10 @@ package api
 
 import (
 	"net/http"
+	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/state"
 )
 
 func (api *API) handleOrganizations(w http.ResponseWriter, r *http.Request) {
@@ -13,7 +17,7 @@ func (api *API) handleOrganizations(w http.ResponseWriter, r *http.Request) {
 	response := []map[string]any{
 		{
 			"id":            1,
-			"name":          api.cfg.DefaultOrganizationName,
+			"name":          api.config().DefaultOrganizationName,
 			"slug":          "default-org-slug",
 			"billing_email": "billing@supabase.co",
 			"plan": map[string]any{
@@ -58,7 +62,21 @@ func (api *API) handleProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project := api.defaultProject()
+	projects := []any{}
+	for _, project := range api.config().AllProjects() {
+		summary := api.projectSummary(project)
+		projects = append(projects, map[string]any{
+			"id":               summary["id"],
+			"ref":              summary["ref"],
+			"name":             summary["name"],
+			"organization_id":  summary["organization_id"],
+			"cloud_provider":   summary["cloud_provider"],
+			"status":           summary["status"],
+			"region":           summary["region"],
+			"inserted_at":      summary["inserted_at"],
+			"connectionString": "",
+		})
+	}
 	response := map[string]any{
 		"id":            1,
 		"primary_email": "johndoe@supabase.io",
@@ -68,25 +86,21 @@ func (api *API) handleProfile(w http.ResponseWriter, r *http.Request) {
 		"organizations": []any{
 			map[string]any{
 				"id":            1,
-				"name":          api.cfg.DefaultOrganizationName,
+				"name":          api.config().DefaultOrganizationName,
 				"slug":          "default-org-slug",
 				"billing_email": "billing@supabase.co",
-				"projects": []any{
-					map[string]any{
-						"id":               project["id"],
-						"ref":              project["ref"],
-						"name":             project["name"],
-						"organization_id":  project["organization_id"],
-						"cloud_provider":   project["cloud_provider"],
-						"status":           project["status"],
-						"region":           project["region"],
-						"inserted_at":      project["inserted_at"],
-						"connectionString": "",
-					},
-				},
+				"projects":      projects,
 			},
 		},
 	}
+	if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+		firstName, lastName, _ := strings.Cut(strings.TrimSpace(identity.Name), " ")
+		response["primary_email"] = identity.Email
+		response["username"] = identity.Username
+		response["first_name"] = firstName
+		response["last_name"] = strings.TrimSpace(lastName)
+		response["groups"] = identity.Groups
+	}
 	writeJSON(w, http.StatusOK, response)
 }
 
@@ -95,7 +109,7 @@ func (api *API) handlePropsProject(w http.ResponseWriter, r *http.Request) {
 		writeMethodNotAllowed(w, r, "GET")
 		return
 	}
-	project := api.defaultProject()
+	project := api.projectSummary(api.project(r))
 	response := map[string]any{
 		"project": map[string]any{
 			"id":              project["id"],
@@ -118,8 +132,9 @@ func (api *API) handlePropsProjectAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project := api.defaultProject()
-	endpoint := api.projectEndpoint()
+	current := api.project(r)
+	project := api.projectSummary(current)
+	endpoint := projectEndpoint(current)
 	response := map[string]any{
 		"project": map[string]any{
 			"id":                         project["id"],
@@ -168,7 +183,7 @@ func (api *API) handlePropsProjectAPI(w http.ResponseWriter, r *http.Request) {
 			"id":   1,
 			"name": "Default API",
 			"project": map[string]any{
-				"ref": "default",
+				"ref": current.Ref,
 			},
 			"app": map[string]any{
 				"id":   1,
@@ -181,9 +196,9 @@ func (api *API) handlePropsProjectAPI(w http.ResponseWriter, r *http.Request) {
 			},
 			"protocol":      endpoint.protocol,
 			"endpoint":      endpoint.host,
-			"restUrl":       api.projectRestURL(),
-			"defaultApiKey": api.cfg.SupabaseAnonKey,
-			"serviceApiKey": api.cfg.SupabaseServiceKey,
+			"restUrl":       projectRestURL(current),
+			"defaultApiKey": current.SupabaseAnonKey,
+			"serviceApiKey": projectSecret(r, current.SupabaseServiceKey),
 			"service_api_keys": []any{
 				map[string]any{
 					"api_key_encrypted": "-",
@@ -211,7 +226,7 @@ func (api *API) handlePropsOrg(w http.ResponseWriter, r *http.Request) {
 	response := map[string]any{
 		"organization": map[string]any{
 			"id":            1,
-			"name":          api.cfg.DefaultOrganizationName,
+			"name":          api.config().DefaultOrganizationName,
 			"slug":          "default-org-slug",
 			"billing_email": "billing@supabase.co",
 			"plan": map[string]any{
@@ -266,16 +281,12 @@ func (api *API) handleTelemetryEvent(w http.ResponseWriter, r *http.Request) {
 func (api *API) handleDatabasePooling(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		writeJSON(w, http.StatusOK, map[string]any{
-			"project": map[string]any{
-				"db_port":           6543,
-				"pool_mode":         "transaction",
-				"pgbouncer_enabled": true,
-				"pgbouncer_status":  "COMING_UP",
-			},
-		})
+		saved := api.savedProject(api.project(r).Ref).Pooling
+		writeJSON(w, http.StatusOK, map[string]any{"project": mergeSettings(defaultPoolingConfig(), saved)})
 	case http.MethodPatch:
-		writeJSON(w, http.StatusOK, map[string]any{})
+		if saved, ok := api.patchSettings(w, r, poolingSettings, func(p *state.Project) *map[string]any { return &p.Pooling }, validatePooling); ok {
+			writeJSON(w, http.StatusOK, mergeSettings(defaultPoolingConfig(), saved))
+		}
 	default:
 		writeMethodNotAllowed(w, r, "GET, PATCH")
 	}
@@ -0,0 +1,413 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresURL is the connection string for project's database as its read-write or
+// read-only user.
+func postgresURL(project config.Project, readOnly bool) string {
+	user := project.PostgresUserReadWrite
+	if readOnly {
+		user = project.PostgresUserReadOnly
+	}
+	databaseURL := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(user, project.PostgresPassword),
+		Host:   net.JoinHostPort(project.PostgresHost, project.PostgresPort),
+		Path:   "/" + project.PostgresDatabase,
+	}
+	return databaseURL.String()
+}
+
+type postgresPoolKey struct {
+	ref      string
+	readOnly bool
+}
+
+// postgresPoolSettings are the settings a pool was created with.
+type postgresPoolSettings struct {
+	databaseURL      string
+	maxConns         int
+	statementTimeout time.Duration
+}
+
+// postgresPool counts the requests using pool, so a replaced pool is closed only once the
+// last of them is done with it.
+type postgresPool struct {
+	settings postgresPoolSettings
+	pool     *pgxpool.Pool
+	users    int
+	retired  bool
+}
+
+// postgresPools holds a pool per project and role for the direct Postgres mode. Pools are
+// created on first use and replaced when their settings change, such as after a reload or a
+// rotated password.
+type postgresPools struct {
+	mu     sync.Mutex
+	pools  map[postgresPoolKey]*postgresPool
+	closed bool
+}
+
+// get returns the pool for project and role. The caller must call release once it no
+// longer uses the pool.
+func (p *postgresPools) get(project config.Project, readOnly bool, cfg config.Config) (pool *pgxpool.Pool, release func(), err error) {
+	key := postgresPoolKey{ref: project.Ref, readOnly: readOnly}
+	settings := postgresPoolSettings{
+		databaseURL:      postgresURL(project, readOnly),
+		maxConns:         cfg.PostgresMaxConns,
+		statementTimeout: cfg.PostgresStatementTimeout,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, nil, errors.New("postgres pools are closed")
+	}
+	existing, ok := p.pools[key]
+	if ok && existing.settings == settings {
+		return existing.pool, p.acquire(existing), nil
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(settings.databaseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if settings.maxConns > 0 {
+		poolConfig.MaxConns = int32(settings.maxConns)
+	}
+	poolConfig.ConnConfig.RuntimeParams["application_name"] = "supabase-studio-go"
+	if settings.statementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(settings.statementTimeout.Milliseconds(), 10)
+	}
+	// Connections are shared between dashboard users, whose SQL may change the role, the
+	// search_path, settings or temporary tables, so each one is reset before it is reused.
+	poolConfig.AfterRelease = resetSession
+	pool, err = pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		existing.retire()
+	}
+	if p.pools == nil {
+		p.pools = map[postgresPoolKey]*postgresPool{}
+	}
+	entry := &postgresPool{settings: settings, pool: pool}
+	p.pools[key] = entry
+	return pool, p.acquire(entry), nil
+}
+
+// acquire counts a user of entry and returns the function that ends its use. p.mu is held.
+func (p *postgresPools) acquire(entry *postgresPool) func() {
+	entry.users++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			entry.users--
+			if entry.retired && entry.users == 0 {
+				go entry.pool.Close()
+			}
+		})
+	}
+}
+
+// retire closes the pool once no request uses it. p.mu is held.
+func (e *postgresPool) retire() {
+	e.retired = true
+	if e.users == 0 {
+		go e.pool.Close()
+	}
+}
+
+// close retires every pool, so each is closed as soon as its last query finishes, and
+// refuses new ones.
+func (p *postgresPools) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for key, entry := range p.pools {
+		entry.retire()
+		delete(p.pools, key)
+	}
+}
+
+// resetSession returns conn to the state of a new session, with the settings it connected
+// with. Connections that cannot be reset are closed instead of going back to the pool.
+func resetSession(conn *pgx.Conn) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := conn.PgConn().Exec(ctx, "DISCARD ALL").ReadAll(); err != nil {
+		slog.Warn("failed to reset postgres session, closing the connection", "error", err)
+		return false
+	}
+	return true
+}
+
+// queryReadOnly reports whether r's SQL must run as the read-only user: when asked to, and
+// always for viewers.
+func queryReadOnly(r *http.Request, readOnly bool) bool {
+	return readOnly || !auth.RoleFromContext(r.Context()).Allows(auth.RoleDeveloper)
+}
+
+// postgresExecute runs query over the project's pool and returns the rows in the shape
+// pg-meta's query endpoint produces, with pgMetaExecute's results.
+func (api *API) postgresExecute(r *http.Request, query string, readOnly bool) ([]byte, *pgMetaError, int, error) {
+	project := api.project(r)
+	readOnly = queryReadOnly(r, readOnly)
+	pool, release, err := api.postgres.get(project, readOnly, api.config())
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	defer release()
+
+	rows, err := runQuery(r.Context(), pool, query)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		metaErr := &pgMetaError{Message: pgErr.Message, Code: pgErr.Code, FormattedError: formatPgError(pgErr, query)}
+		body, _ := json.Marshal(map[string]any{
+			"error":          metaErr.Message,
+			"message":        metaErr.Message,
+			"code":           metaErr.Code,
+			"formattedError": metaErr.FormattedError,
+		})
+		return body, metaErr, http.StatusBadRequest, nil
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "postgres query failed", "project", project.Ref, "read_only", readOnly, "error", err)
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	body, err := json.Marshal(rows)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	return body, nil, http.StatusOK, nil
+}
+
+// runQuery runs query over the simple protocol, so it may hold several statements. Like
+// pg-meta, it returns the rows of the last statement that returned any.
+func runQuery(ctx context.Context, pool *pgxpool.Pool, query string) ([]map[string]any, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	results, err := conn.Conn().PgConn().Exec(ctx, query).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := []map[string]any{}
+	for _, result := range results {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		if len(result.Rows) == 0 {
+			continue
+		}
+		rows = make([]map[string]any, 0, len(result.Rows))
+		for _, values := range result.Rows {
+			row := make(map[string]any, len(values))
+			for i, field := range result.FieldDescriptions {
+				row[field.Name] = decodeText(field.DataTypeOID, values[i])
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// decodeText converts a value in Postgres text format to the JSON value pg-meta returns for
+// its type: numbers and booleans as JSON, int8 as a number while it is exact, json as is,
+// bytea as a serialized Buffer, arrays element by element, and everything else, including
+// numeric and dates, as the text Postgres sent.
+func decodeText(oid uint32, value []byte) any {
+	if value == nil {
+		return nil
+	}
+	text := string(value)
+	switch oid {
+	case pgtype.BoolOID:
+		return text == "t"
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.OIDOID:
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+	case pgtype.Int8OID:
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil && n >= -(1<<53-1) && n <= 1<<53-1 {
+			return n
+		}
+	case pgtype.Float4OID, pgtype.Float8OID:
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil
+			}
+			return f
+		}
+	case pgtype.JSONOID, pgtype.JSONBOID:
+		if json.Valid(value) {
+			return json.RawMessage(value)
+		}
+	case pgtype.ByteaOID:
+		if data, err := hex.DecodeString(strings.TrimPrefix(text, `\x`)); err == nil {
+			bytes := make([]int, len(data))
+			for i, b := range data {
+				bytes[i] = int(b)
+			}
+			return map[string]any{"type": "Buffer", "data": bytes}
+		}
+	default:
+		if element, ok := arrayElementOIDs[oid]; ok {
+			if parsed, ok := parseArray(text, element); ok {
+				return parsed
+			}
+		}
+	}
+	return text
+}
+
+// arrayElementOIDs maps the array types pg-meta returns as JSON arrays to their elements.
+var arrayElementOIDs = map[uint32]uint32{
+	pgtype.BoolArrayOID:        pgtype.BoolOID,
+	pgtype.Int2ArrayOID:        pgtype.Int2OID,
+	pgtype.Int4ArrayOID:        pgtype.Int4OID,
+	pgtype.Int8ArrayOID:        pgtype.Int8OID,
+	pgtype.OIDArrayOID:         pgtype.OIDOID,
+	pgtype.Float4ArrayOID:      pgtype.Float4OID,
+	pgtype.Float8ArrayOID:      pgtype.Float8OID,
+	pgtype.NumericArrayOID:     pgtype.NumericOID,
+	pgtype.TextArrayOID:        pgtype.TextOID,
+	pgtype.VarcharArrayOID:     pgtype.VarcharOID,
+	pgtype.BPCharArrayOID:      pgtype.BPCharOID,
+	pgtype.NameArrayOID:        pgtype.NameOID,
+	pgtype.QCharArrayOID:       pgtype.QCharOID,
+	pgtype.UUIDArrayOID:        pgtype.UUIDOID,
+	pgtype.JSONArrayOID:        pgtype.JSONOID,
+	pgtype.JSONBArrayOID:       pgtype.JSONBOID,
+	pgtype.DateArrayOID:        pgtype.DateOID,
+	pgtype.TimestampArrayOID:   pgtype.TimestampOID,
+	pgtype.TimestamptzArrayOID: pgtype.TimestamptzOID,
+}
+
+// parseArray parses an array literal such as {1,NULL,"a b",{2,3}} into nested slices.
+func parseArray(text string, element uint32) ([]any, bool) {
+	if i := strings.Index(text, "={"); strings.HasPrefix(text, "[") && i > 0 {
+		// Drop explicit bounds such as [0:1]={1,2}.
+		text = text[i+1:]
+	}
+	parsed, rest, ok := parseArrayLevel(text, element)
+	return parsed, ok && rest == ""
+}
+
+func parseArrayLevel(text string, element uint32) ([]any, string, bool) {
+	if !strings.HasPrefix(text, "{") {
+		return nil, text, false
+	}
+	text = text[1:]
+	items := []any{}
+	if strings.HasPrefix(text, "}") {
+		return items, text[1:], true
+	}
+	for {
+		switch {
+		case strings.HasPrefix(text, "{"):
+			nested, rest, ok := parseArrayLevel(text, element)
+			if !ok {
+				return nil, text, false
+			}
+			items = append(items, nested)
+			text = rest
+		case strings.HasPrefix(text, `"`):
+			var value strings.Builder
+			i := 1
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				value.WriteByte(text[i])
+			}
+			if i >= len(text) {
+				return nil, text, false
+			}
+			items = append(items, decodeText(element, []byte(value.String())))
+			text = text[i+1:]
+		default:
+			end := strings.IndexAny(text, ",}")
+			if end < 0 {
+				return nil, text, false
+			}
+			if raw := text[:end]; raw == "NULL" {
+				items = append(items, nil)
+			} else {
+				items = append(items, decodeText(element, []byte(raw)))
+			}
+			text = text[end:]
+		}
+
+		if text == "" {
+			return nil, text, false
+		}
+		if text[0] == '}' {
+			return items, text[1:], true
+		}
+		// Every array type used here is delimited by commas.
+		if text[0] != ',' {
+			return nil, text, false
+		}
+		text = text[1:]
+	}
+}
+
+// formatPgError renders err the way pg-meta's formattedError does, pointing at the error's
+// position in query when Postgres reports one.
+func formatPgError(err *pgconn.PgError, query string) string {
+	severity := err.Severity
+	if severity == "" {
+		severity = "ERROR"
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s:  %s: %s\n", severity, err.Code, err.Message)
+	if err.Position > 0 && int(err.Position) <= len([]rune(query))+1 {
+		runes := []rune(query)
+		offset := int(err.Position) - 1
+		lineNumber := strings.Count(string(runes[:min(offset, len(runes))]), "\n") + 1
+		lines := strings.Split(query, "\n")
+		line := lines[lineNumber-1]
+		lineStart := 0
+		for _, previous := range lines[:lineNumber-1] {
+			lineStart += len([]rune(previous)) + 1
+		}
+		prefix := fmt.Sprintf("LINE %d: ", lineNumber)
+		fmt.Fprintf(&out, "%s%s\n%s^\n", prefix, line, strings.Repeat(" ", len(prefix)+offset-lineStart))
+	}
+	if err.Detail != "" {
+		fmt.Fprintf(&out, "DETAIL:  %s\n", err.Detail)
+	}
+	if err.Hint != "" {
+		fmt.Fprintf(&out, "HINT:  %s\n", err.Hint)
+	}
+	return out.String()
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestDecodeTextMatchesPgMetaJSON(t *testing.T) {
+	cases := []struct {
+		oid   uint32
+		value string
+		want  string
+	}{
+		{pgtype.BoolOID, "t", `true`},
+		{pgtype.Int4OID, "42", `42`},
+		{pgtype.Int8OID, "9007199254740991", `9007199254740991`},
+		{pgtype.Int8OID, "9007199254740993", `"9007199254740993"`},
+		{pgtype.Float8OID, "1.5", `1.5`},
+		{pgtype.NumericOID, "1.50", `"1.50"`},
+		{pgtype.TimestamptzOID, "2024-01-02 03:04:05+00", `"2024-01-02 03:04:05+00"`},
+		{pgtype.JSONBOID, `{"a": [1, 2]}`, `{"a":[1,2]}`},
+		{pgtype.ByteaOID, `\x0aff`, `{"data":[10,255],"type":"Buffer"}`},
+		{pgtype.TextArrayOID, `{a,"b c",NULL,"d\"e"}`, `["a","b c",null,"d\"e"]`},
+		{pgtype.Int4ArrayOID, `{{1,2},{3,4}}`, `[[1,2],[3,4]]`},
+		{pgtype.Int4ArrayOID, `[0:1]={5,6}`, `[5,6]`},
+		{pgtype.TextArrayOID, `{}`, `[]`},
+	}
+	for _, tc := range cases {
+		got, err := json.Marshal(decodeText(tc.oid, []byte(tc.value)))
+		if err != nil {
+			t.Fatalf("marshal %q: %v", tc.value, err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("decodeText(%d, %q) = %s, want %s", tc.oid, tc.value, got, tc.want)
+		}
+	}
+	if got := decodeText(pgtype.TextOID, nil); got != nil {
+		t.Errorf("expected NULL to decode to nil, got %v", got)
+	}
+}
+
+func TestDecodedInt8FeedsQueryInt64(t *testing.T) {
+	body, _ := json.Marshal([]map[string]any{{"size": decodeText(pgtype.Int8OID, []byte("123456"))}})
+	var rows []map[string]any
+	if err := json.Unmarshal(body, &rows); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got, err := int64FromAny(rows[0]["size"]); err != nil || got != 123456 {
+		t.Fatalf("expected 123456, got %d, %v", got, err)
+	}
+}
+
+func TestFormatPgErrorPointsAtPosition(t *testing.T) {
+	got := formatPgError(&pgconn.PgError{
+		Severity: "ERROR",
+		Code:     "42601",
+		Message:  `syntax error at or near "fro"`,
+		Position: 18,
+		Hint:     "Check the query.",
+	}, "select 1;\nselect fro")
+	want := "ERROR:  42601: syntax error at or near \"fro\"\n" +
+		"LINE 2: select fro\n" +
+		"               ^\n" +
+		"HINT:  Check the query.\n"
+	if got != want {
+		t.Fatalf("unexpected formattedError:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPostgresURLEscapesCredentials(t *testing.T) {
+	got := postgresURL(config.Project{
+		PostgresHost:         "db",
+		PostgresPort:         "5432",
+		PostgresDatabase:     "postgres",
+		PostgresPassword:     "p@ss/word",
+		PostgresUserReadOnly: "reader",
+	}, true)
+	if want := "postgresql://reader:p%40ss%2Fword@db:5432/postgres"; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestPostgresPoolsResetSessionsAndBoundStatements(t *testing.T) {
+	var pools postgresPools
+	defer pools.close()
+	project := config.Project{
+		Ref:                   "default",
+		PostgresHost:          "db",
+		PostgresPort:          "5432",
+		PostgresDatabase:      "postgres",
+		PostgresPassword:      "secret",
+		PostgresUserReadWrite: "supabase_admin",
+	}
+	cfg := config.Config{PostgresMaxConns: 2, PostgresStatementTimeout: 2 * time.Minute}
+
+	pool, release, err := pools.get(project, false, cfg)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer release()
+	poolConfig := pool.Config()
+	if got := poolConfig.ConnConfig.RuntimeParams["statement_timeout"]; got != "120000" {
+		t.Fatalf("expected a 120000ms statement_timeout, got %q", got)
+	}
+	if poolConfig.AfterRelease == nil {
+		t.Fatal("expected released connections to be reset")
+	}
+
+	again, releaseAgain, _ := pools.get(project, false, cfg)
+	releaseAgain()
+	if again != pool {
+		t.Fatal("expected the pool to be reused while its settings are unchanged")
+	}
+	cfg.PostgresStatementTimeout = 30 * time.Second
+	replaced, releaseReplaced, err := pools.get(project, false, cfg)
+	if err != nil || replaced == pool {
+		t.Fatalf("expected a new pool after the statement timeout changed, got %v", err)
+	}
+	defer releaseReplaced()
+}
+
+func TestPostgresPoolsKeepReplacedPoolsOpenWhileInUse(t *testing.T) {
+	var pools postgresPools
+	project := config.Project{Ref: "default", PostgresHost: "db", PostgresPort: "5432", PostgresDatabase: "postgres"}
+
+	old, release, err := pools.get(project, false, config.Config{PostgresMaxConns: 2})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if _, releaseNew, err := pools.get(project, false, config.Config{PostgresMaxConns: 3}); err != nil {
+		t.Fatalf("get: %v", err)
+	} else {
+		releaseNew()
+	}
+	if poolClosed(old) {
+		t.Fatal("expected the replaced pool to stay open while a request holds it")
+	}
+
+	pools.close()
+	release()
+	deadline := time.Now().Add(5 * time.Second)
+	for !poolClosed(old) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the replaced pool to close after its last user released it")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, _, err := pools.get(project, false, config.Config{}); err == nil {
+		t.Fatal("expected no pools after close")
+	}
+}
+
+func poolClosed(pool *pgxpool.Pool) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	conn, err := pool.Acquire(ctx)
+	if err == nil {
+		conn.Release()
+	}
+	return err != nil && strings.Contains(err.Error(), "closed pool")
+}
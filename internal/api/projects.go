@@ -10,6 +10,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/state"
 )
 
 type endpointInfo struct {
@@ -18,43 +21,46 @@ type endpointInfo struct {
 	origin   string
 }
 
-func (api *API) getProjectName() string {
-	api.mu.RLock()
-	defer api.mu.RUnlock()
-
-	if api.projectName == "" {
-		return api.cfg.DefaultProjectName
+// project returns the project named by the request's {ref}, or the default project for
+// routes without one. resolveProject has rejected unknown refs before handlers run.
+func (api *API) project(r *http.Request) config.Project {
+	cfg := api.config()
+	if project, ok := cfg.Project(chiURLParam(r, "ref")); ok {
+		return project
 	}
-	return api.projectName
+	project, _ := cfg.Project(config.DefaultProjectRef)
+	return project
 }
 
-func (api *API) setProjectName(name string) {
-	api.mu.Lock()
-	defer api.mu.Unlock()
-	api.projectName = name
+// projectID numbers projects in registry order, starting with 1 for the default project.
+func (api *API) projectID(ref string) int {
+	for i, project := range api.config().AllProjects() {
+		if project.Ref == ref {
+			return i + 1
+		}
+	}
+	return 1
 }
 
-func (api *API) getProjectDiskSize() int {
-	api.mu.RLock()
-	defer api.mu.RUnlock()
-
-	if api.projectDiskSize <= 0 {
-		if api.cfg.DefaultProjectDiskSizeGB <= 0 {
-			return 8
-		}
-		return api.cfg.DefaultProjectDiskSizeGB
+func (api *API) getProjectName(project config.Project) string {
+	if name := api.savedProject(project.Ref).Name; name != "" {
+		return name
 	}
-	return api.projectDiskSize
+	return project.Name
 }
 
-func (api *API) setProjectDiskSize(size int) {
-	api.mu.Lock()
-	defer api.mu.Unlock()
-	api.projectDiskSize = size
+func (api *API) getProjectDiskSize(project config.Project) int {
+	if size := api.savedProject(project.Ref).DiskSizeGB; size > 0 {
+		return size
+	}
+	if project.DiskSizeGB > 0 {
+		return project.DiskSizeGB
+	}
+	return 8
 }
 
-func (api *API) projectEndpoint() endpointInfo {
-	publicURL := api.cfg.SupabasePublicURL
+func projectEndpoint(project config.Project) endpointInfo {
+	publicURL := project.SupabasePublicURL
 	if publicURL == "" {
 		publicURL = "http://localhost:8000"
 	}
@@ -69,18 +75,18 @@ func (api *API) projectEndpoint() endpointInfo {
 	}
 }
 
-func (api *API) projectRestURL() string {
-	endpoint := api.projectEndpoint()
+func projectRestURL(project config.Project) string {
+	endpoint := projectEndpoint(project)
 	return endpoint.origin + "/rest/v1/"
 }
 
-func (api *API) defaultProject() map[string]any {
-	diskSize := api.getProjectDiskSize()
+func (api *API) projectSummary(project config.Project) map[string]any {
+	diskSize := api.getProjectDiskSize(project)
 
 	return map[string]any{
-		"id":                  1,
-		"ref":                 "default",
-		"name":                api.getProjectName(),
+		"id":                  api.projectID(project.Ref),
+		"ref":                 project.Ref,
+		"name":                api.getProjectName(project),
 		"organization_id":     1,
 		"cloud_provider":      "localhost",
 		"status":              "ACTIVE_HEALTHY",
@@ -96,7 +102,11 @@ func (api *API) handleProjectsList(w http.ResponseWriter, r *http.Request) {
 		writeMethodNotAllowed(w, r, "GET")
 		return
 	}
-	writeJSON(w, http.StatusOK, []any{api.defaultProject()})
+	projects := []any{}
+	for _, project := range api.config().AllProjects() {
+		projects = append(projects, api.projectSummary(project))
+	}
+	writeJSON(w, http.StatusOK, projects)
 }
 
 func (api *API) handleProjectDetail(w http.ResponseWriter, r *http.Request) {
@@ -104,9 +114,10 @@ func (api *API) handleProjectDetail(w http.ResponseWriter, r *http.Request) {
 		writeMethodNotAllowed(w, r, "GET")
 		return
 	}
-	response := api.defaultProject()
+	project := api.project(r)
+	response := api.projectSummary(project)
 	response["connectionString"] = ""
-	response["restUrl"] = api.projectRestURL()
+	response["restUrl"] = projectRestURL(project)
 	writeJSON(w, http.StatusOK, response)
 }
 
@@ -141,7 +152,8 @@ func (api *API) handleProjectUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := api.updateProjectName(name); err != nil {
+	project := api.project(r)
+	if err := api.updateProject(r.Context(), project.Ref, func(saved *state.Project) { saved.Name = name }); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"error": map[string]any{"message": "Failed to persist project settings"},
 		})
@@ -149,8 +161,8 @@ func (api *API) handleProjectUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
-		"id":   1,
-		"ref":  "default",
+		"id":   api.projectID(project.Ref),
+		"ref":  project.Ref,
 		"name": name,
 	})
 }
@@ -161,7 +173,7 @@ func (api *API) handleProjectDisk(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]any{
 			"attributes": map[string]any{
 				"iops":             3000,
-				"size_gb":          api.getProjectDiskSize(),
+				"size_gb":          api.getProjectDiskSize(api.project(r)),
 				"throughput_mbps":  125,
 				"throughput_mibps": 125,
 				"type":             "gp3",
@@ -188,7 +200,7 @@ func (api *API) handleProjectDisk(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := api.updateProjectDiskSize(payload.Attributes.SizeGB); err != nil {
+		if err := api.updateProject(r.Context(), api.project(r).Ref, func(saved *state.Project) { saved.DiskSizeGB = payload.Attributes.SizeGB }); err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{
 				"error": map[string]any{"message": "Failed to persist disk settings"},
 			})
@@ -251,7 +263,7 @@ func (api *API) handleProjectDiskUtilization(w http.ResponseWriter, r *http.Requ
 		systemBytes = 0
 	}
 
-	totalSizeBytes := int64(api.getProjectDiskSize()) * bytesPerGiB
+	totalSizeBytes := int64(api.getProjectDiskSize(api.project(r))) * bytesPerGiB
 	usedBytes := databaseSizeBytes + walSizeBytes + systemBytes
 	if usedBytes > totalSizeBytes {
 		usedBytes = totalSizeBytes
@@ -294,7 +306,7 @@ func (api *API) handleProjectResize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := api.updateProjectDiskSize(payload.VolumeSizeGB); err != nil {
+	if err := api.updateProject(r.Context(), api.project(r).Ref, func(saved *state.Project) { saved.DiskSizeGB = payload.VolumeSizeGB }); err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{
 			"error": map[string]any{"message": "Failed to persist disk settings"},
 		})
@@ -361,7 +373,8 @@ func (api *API) handleProjectSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	endpoint := api.projectEndpoint()
+	project := api.project(r)
+	endpoint := projectEndpoint(project)
 	response := map[string]any{
 		"app_config": map[string]any{
 			"db_schema":        "public",
@@ -377,18 +390,18 @@ func (api *API) handleProjectSettings(w http.ResponseWriter, r *http.Request) {
 		"db_port":           5432,
 		"db_user":           "postgres",
 		"inserted_at":       "2021-08-02T06:40:40.646Z",
-		"jwt_secret":        api.cfg.AuthJWTSecret,
-		"name":              api.getProjectName(),
-		"ref":               "default",
+		"jwt_secret":        projectSecret(r, project.AuthJWTSecret),
+		"name":              api.getProjectName(project),
+		"ref":               project.Ref,
 		"region":            "ap-southeast-1",
 		"service_api_keys": []any{
 			map[string]any{
-				"api_key": api.cfg.SupabaseServiceKey,
+				"api_key": projectSecret(r, project.SupabaseServiceKey),
 				"name":    "service_role key",
 				"tags":    "service_role",
 			},
 			map[string]any{
-				"api_key": api.cfg.SupabaseAnonKey,
+				"api_key": project.SupabaseAnonKey,
 				"name":    "anon key",
 				"tags":    "anon",
 			},
@@ -406,6 +419,7 @@ func (api *API) handleProjectDatabases(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	project := api.project(r)
 	response := []any{
 		map[string]any{
 			"cloud_provider":              "localhost",
@@ -415,10 +429,10 @@ func (api *API) handleProjectDatabases(w http.ResponseWriter, r *http.Request) {
 			"db_name":                     "postgres",
 			"db_port":                     5432,
 			"db_user":                     "postgres",
-			"identifier":                  "default",
+			"identifier":                  project.Ref,
 			"inserted_at":                 "",
 			"region":                      "local",
-			"restUrl":                     api.projectRestURL(),
+			"restUrl":                     projectRestURL(project),
 			"size":                        "",
 			"status":                      "ACTIVE_HEALTHY",
 		},
@@ -436,13 +450,14 @@ func (api *API) handleProjectRest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target := strings.TrimSuffix(api.cfg.SupabaseURL, "/") + "/rest/v1/"
+	project := api.project(r)
+	target := strings.TrimSuffix(project.SupabaseURL, "/") + "/rest/v1/"
 	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, target, nil)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": "Internal Server Error"}})
 		return
 	}
-	req.Header.Set("apikey", api.cfg.SupabaseServiceKey)
+	req.Header.Set("apikey", project.SupabaseServiceKey)
 	resp, err := api.client.Do(req)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": "Internal Server Error"}})
@@ -466,18 +481,19 @@ func (api *API) handleProjectGraphql(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	project := api.project(r)
 	authorization := r.Header.Get("x-graphql-authorization")
 	if authorization == "" {
-		authorization = "Bearer " + api.cfg.SupabaseAnonKey
+		authorization = "Bearer " + project.SupabaseAnonKey
 	}
 	body, _ := readRawBody(r)
-	target := strings.TrimSuffix(api.cfg.SupabaseURL, "/") + "/graphql/v1"
+	target := strings.TrimSuffix(project.SupabaseURL, "/") + "/graphql/v1"
 	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, target, bytes.NewReader(body))
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": "Internal Server Error"}})
 		return
 	}
-	req.Header.Set("apikey", api.cfg.SupabaseServiceKey)
+	req.Header.Set("apikey", project.SupabaseServiceKey)
 	req.Header.Set("Authorization", authorization)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -503,7 +519,7 @@ func (api *API) handleProjectTempAPIKey(w http.ResponseWriter, r *http.Request)
 		writeMethodNotAllowed(w, r, "POST")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"api_key": api.cfg.SupabaseServiceKey})
+	writeJSON(w, http.StatusOK, map[string]any{"api_key": api.project(r).SupabaseServiceKey})
 }
 
 func (api *API) handleProjectInfraMonitoring(w http.ResponseWriter, r *http.Request) {
@@ -525,7 +541,7 @@ func (api *API) handleProjectBillingAddons(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{
-		"ref":              "",
+		"ref":              api.project(r).Ref,
 		"selected_addons":  []any{},
 		"available_addons": []any{},
 	})
@@ -534,18 +550,13 @@ func (api *API) handleProjectBillingAddons(w http.ResponseWriter, r *http.Reques
 func (api *API) handleProjectConfig(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		writeJSON(w, http.StatusOK, map[string]any{
-			"db_anon_role":         "anon",
-			"db_extra_search_path": "public",
-			"db_schema":            "public, storage",
-			"jwt_secret":           api.cfg.AuthJWTSecret,
-			"max_rows":             100,
-			"role_claim_key":       ".role",
-		})
+		writeJSON(w, http.StatusOK, api.postgrestConfig(r))
 	case http.MethodPatch:
-		writeJSON(w, http.StatusOK, map[string]any{})
+		if _, ok := api.patchSettings(w, r, postgrestSettings, func(p *state.Project) *map[string]any { return &p.PostgrestConfig }, nil); ok {
+			writeJSON(w, http.StatusOK, api.postgrestConfig(r))
+		}
 	default:
-		writeMethodNotAllowed(w, r, "GET")
+		writeMethodNotAllowed(w, r, "GET, PATCH")
 	}
 }
 
@@ -554,14 +565,14 @@ func (api *API) handleProjectPostgrestConfig(w http.ResponseWriter, r *http.Requ
 		writeMethodNotAllowed(w, r, "GET")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
-		"db_anon_role":         "anon",
-		"db_extra_search_path": "public",
-		"db_schema":            "public, storage",
-		"jwt_secret":           api.cfg.AuthJWTSecret,
-		"max_rows":             100,
-		"role_claim_key":       ".role",
-	})
+	writeJSON(w, http.StatusOK, api.postgrestConfig(r))
+}
+
+func (api *API) postgrestConfig(r *http.Request) map[string]any {
+	project := api.project(r)
+	settings := mergeSettings(defaultPostgrestConfig(), api.savedProject(project.Ref).PostgrestConfig)
+	settings["jwt_secret"] = projectSecret(r, project.AuthJWTSecret)
+	return settings
 }
 
 func (api *API) handleProjectAnalyticsEndpoint(w http.ResponseWriter, r *http.Request) {
@@ -597,14 +608,15 @@ func (api *API) handleProjectAnalyticsEndpoint(w http.ResponseWriter, r *http.Re
 }
 
 func (api *API) handleProjectLogDrains(w http.ResponseWriter, r *http.Request) {
-	if missing := api.missingLogflareEnv(); len(missing) > 0 {
+	project := api.project(r)
+	if missing := missingLogflareEnv(project); len(missing) > 0 {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": strings.Join(missing, ", ") + " env variables are not set"}})
 		return
 	}
 
 	switch r.Method {
 	case http.MethodGet:
-		url := api.cfg.LogflareURL + "/api/backends?metadata[type]=log-drain"
+		url := project.LogflareURL + "/api/backends?metadata[type]=log-drain"
 		api.logflareProxy(w, r, http.MethodGet, url, nil)
 	case http.MethodPost:
 		body, _ := readRawBody(r)
@@ -612,14 +624,14 @@ func (api *API) handleProjectLogDrains(w http.ResponseWriter, r *http.Request) {
 		_ = json.Unmarshal(body, &payload)
 		payload["metadata"] = map[string]any{"type": "log-drain"}
 		body, _ = json.Marshal(payload)
-		url := api.cfg.LogflareURL + "/api/backends"
+		url := project.LogflareURL + "/api/backends"
 		respBody, status, err := api.logflareRaw(r, http.MethodPost, url, body)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": err.Error()}})
 			return
 		}
 
-		sourcesBody, _, _ := api.logflareRaw(r, http.MethodGet, api.cfg.LogflareURL+"/api/sources", nil)
+		sourcesBody, _, _ := api.logflareRaw(r, http.MethodGet, project.LogflareURL+"/api/sources", nil)
 		var sources []map[string]any
 		_ = json.Unmarshal(sourcesBody, &sources)
 
@@ -638,7 +650,7 @@ func (api *API) handleProjectLogDrains(w http.ResponseWriter, r *http.Request) {
 				"source_id":  source["id"],
 			}
 			bodyRule, _ := json.Marshal(param)
-			_, _, _ = api.logflareRaw(r, http.MethodPost, api.cfg.LogflareURL+"/api/rules", bodyRule)
+			_, _, _ = api.logflareRaw(r, http.MethodPost, project.LogflareURL+"/api/rules", bodyRule)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -650,7 +662,8 @@ func (api *API) handleProjectLogDrains(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) handleProjectLogDrain(w http.ResponseWriter, r *http.Request) {
-	if missing := api.missingLogflareEnv(); len(missing) > 0 {
+	project := api.project(r)
+	if missing := missingLogflareEnv(project); len(missing) > 0 {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": strings.Join(missing, ", ") + " env variables are not set"}})
 		return
 	}
@@ -659,7 +672,7 @@ func (api *API) handleProjectLogDrain(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"message": "Missing uuid"}})
 		return
 	}
-	target := api.cfg.LogflareURL + "/api/backends/" + uuid
+	target := project.LogflareURL + "/api/backends/" + uuid
 	switch r.Method {
 	case http.MethodGet:
 		api.logflareProxy(w, r, http.MethodGet, target, nil)
@@ -695,12 +708,12 @@ func isAllowedLogSource(name string) bool {
 	}
 }
 
-func (api *API) missingLogflareEnv() []string {
+func missingLogflareEnv(project config.Project) []string {
 	var missing []string
-	if api.cfg.LogflareToken == "" {
+	if project.LogflareToken == "" {
 		missing = append(missing, "LOGFLARE_PRIVATE_ACCESS_TOKEN")
 	}
-	if api.cfg.LogflareURL == "" {
+	if project.LogflareURL == "" {
 		missing = append(missing, "LOGFLARE_URL")
 	}
 	return missing
@@ -715,7 +728,7 @@ func (api *API) logflareRaw(r *http.Request, method, target string, body []byte)
 	if err != nil {
 		return nil, http.StatusInternalServerError, err
 	}
-	req.Header.Set("Authorization", "Bearer "+api.cfg.LogflareToken)
+	req.Header.Set("Authorization", "Bearer "+api.project(r).LogflareToken)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -8,16 +9,18 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
 	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/state"
 )
 
 func testAPIHandler() http.Handler {
-	return NewRouter(config.Config{
+	return newTestRouter(config.NewHolder(config.Config{
 		DefaultProjectName:       "Default Project",
 		DefaultProjectDiskSizeGB: 8,
 		SupabasePublicURL:        "http://localhost:8000",
 		StateFilePath:            "",
-	})
+	}, ""))
 }
 
 func TestProjectUpdateRejectsInvalidName(t *testing.T) {
@@ -78,7 +81,7 @@ func TestProjectUpdatePersistsAcrossRouterRestart(t *testing.T) {
 		StateFilePath:            filepath.Join(t.TempDir(), "supabase-studio-go-state.json"),
 	}
 
-	handler := NewRouter(cfg)
+	handler := newTestRouter(config.NewHolder(cfg, ""))
 
 	updateReq := httptest.NewRequest(http.MethodPatch, "/platform/projects/default", strings.NewReader(`{"name":"Persistent Name"}`))
 	updateReq.Header.Set("Content-Type", "application/json")
@@ -90,7 +93,7 @@ func TestProjectUpdatePersistsAcrossRouterRestart(t *testing.T) {
 	}
 
 	// Simulate process restart by constructing a new router with the same state file.
-	restartedHandler := NewRouter(cfg)
+	restartedHandler := newTestRouter(config.NewHolder(cfg, ""))
 	getRec := httptest.NewRecorder()
 	getReq := httptest.NewRequest(http.MethodGet, "/platform/projects/default", nil)
 	restartedHandler.ServeHTTP(getRec, getReq)
@@ -149,7 +152,7 @@ func TestProjectResizePersistsAcrossRouterRestart(t *testing.T) {
 		StateFilePath:            filepath.Join(t.TempDir(), "supabase-studio-go-state.json"),
 	}
 
-	handler := NewRouter(cfg)
+	handler := newTestRouter(config.NewHolder(cfg, ""))
 
 	resizeReq := httptest.NewRequest(http.MethodPost, "/platform/projects/default/resize", strings.NewReader(`{"volume_size_gb":24}`))
 	resizeReq.Header.Set("Content-Type", "application/json")
@@ -160,7 +163,7 @@ func TestProjectResizePersistsAcrossRouterRestart(t *testing.T) {
 		t.Fatalf("expected status 200, got %d", resizeRec.Code)
 	}
 
-	restartedHandler := NewRouter(cfg)
+	restartedHandler := newTestRouter(config.NewHolder(cfg, ""))
 	getRec := httptest.NewRecorder()
 	getReq := httptest.NewRequest(http.MethodGet, "/platform/projects/default", nil)
 	restartedHandler.ServeHTTP(getRec, getReq)
@@ -180,3 +183,213 @@ func TestProjectResizePersistsAcrossRouterRestart(t *testing.T) {
 		t.Fatalf("expected persisted disk size after restart to be 24GB, got %dGB", payload.VolumeSizeGB)
 	}
 }
+
+func TestProfileUsesAuthenticatedIdentity(t *testing.T) {
+	handler := testAPIHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/platform/profile", nil)
+	req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Name:     "Alice Example",
+		Groups:   []string{"studio-users"},
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var payload struct {
+		Username     string   `json:"username"`
+		PrimaryEmail string   `json:"primary_email"`
+		FirstName    string   `json:"first_name"`
+		LastName     string   `json:"last_name"`
+		Groups       []string `json:"groups"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode profile: %v", err)
+	}
+	if payload.Username != "alice" || payload.PrimaryEmail != "alice@example.com" || payload.FirstName != "Alice" || payload.LastName != "Example" {
+		t.Fatalf("unexpected profile %#v", payload)
+	}
+	if len(payload.Groups) != 1 || payload.Groups[0] != "studio-users" {
+		t.Fatalf("expected groups in profile, got %#v", payload.Groups)
+	}
+}
+
+func TestProjectRoutesResolveUpstreamsFromRef(t *testing.T) {
+	var gotKey string
+	staging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("apikey")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer staging.Close()
+
+	cfg := config.Config{
+		DefaultProjectName:       "Default Project",
+		DefaultProjectDiskSizeGB: 8,
+		SupabaseURL:              "http://127.0.0.1:1",
+		SupabaseServiceKey:       "default-key",
+		StateFilePath:            filepath.Join(t.TempDir(), "supabase-studio-go-state.json"),
+		Projects: []config.Project{
+			{Ref: "staging", Name: "Staging", SupabaseURL: staging.URL, SupabaseServiceKey: "staging-key"},
+		},
+	}
+	handler := newTestRouter(config.NewHolder(cfg, ""))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/platform/storage/staging/buckets", nil))
+	if rec.Code != http.StatusOK || gotKey != "staging-key" {
+		t.Fatalf("expected the staging upstream and key, got status %d and key %q", rec.Code, gotKey)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/platform/projects/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for an unknown ref, got %d", rec.Code)
+	}
+
+	updateReq := httptest.NewRequest(http.MethodPatch, "/platform/projects/staging", strings.NewReader(`{"name":"Staging EU"}`))
+	updateReq.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), updateReq)
+
+	restarted := newTestRouter(config.NewHolder(cfg, ""))
+	rec = httptest.NewRecorder()
+	restarted.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/platform/projects", nil))
+	var projects []struct {
+		ID   int
+		Ref  string
+		Name string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &projects); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(projects) != 2 || projects[0].Name != "Default Project" || projects[1].Ref != "staging" || projects[1].ID != 2 {
+		t.Fatalf("expected both projects in registry order, got %+v", projects)
+	}
+	if projects[1].Name != "Staging EU" {
+		t.Fatalf("expected the renamed project to be persisted, got %q", projects[1].Name)
+	}
+}
+
+func TestSettingsPatchesPersistAcrossRouterRestart(t *testing.T) {
+	cfg := config.Config{
+		DefaultProjectName:       "Default Project",
+		DefaultProjectDiskSizeGB: 8,
+		StateFilePath:            filepath.Join(t.TempDir(), "supabase-studio-go-state.json"),
+	}
+	handler := newTestRouter(config.NewHolder(cfg, ""))
+
+	patch := func(path, body string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := patch("/platform/projects/default/config", `{"max_rows":500,"db_schema":"public","jwt_secret":"ignored"}`); code != http.StatusOK {
+		t.Fatalf("expected status 200 for the PostgREST settings, got %d", code)
+	}
+	if code := patch("/platform/database/default/pooling", `{"pool_mode":"session","default_pool_size":30}`); code != http.StatusOK {
+		t.Fatalf("expected status 200 for the pooler settings, got %d", code)
+	}
+	if code := patch("/platform/database/default/pooling", `{"pool_mode":"statement"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an unknown pool mode, got %d", code)
+	}
+	if code := patch("/platform/projects/default/config", `{"max_rows":"many"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a mistyped setting, got %d", code)
+	}
+
+	restarted := newTestRouter(config.NewHolder(cfg, ""))
+	get := func(path string, payload any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		restarted.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if err := json.Unmarshal(rec.Body.Bytes(), payload); err != nil {
+			t.Fatalf("failed to decode %s response: %v", path, err)
+		}
+	}
+
+	var postgrest map[string]any
+	get("/platform/projects/default/config/postgrest", &postgrest)
+	if postgrest["max_rows"] != float64(500) || postgrest["db_schema"] != "public" || postgrest["db_anon_role"] != "anon" {
+		t.Fatalf("expected saved PostgREST settings over the defaults, got %v", postgrest)
+	}
+	var pooling struct{ Project map[string]any }
+	get("/platform/database/default/pooling", &pooling)
+	if pooling.Project["pool_mode"] != "session" || pooling.Project["default_pool_size"] != float64(30) {
+		t.Fatalf("expected saved pooler settings, got %v", pooling.Project)
+	}
+}
+
+func TestRenameSnippetFolder(t *testing.T) {
+	store := snippetStore{folder: t.TempDir(), projectID: 1}
+	reports, err := store.createFolder("Reports")
+	if err != nil {
+		t.Fatalf("createFolder: %v", err)
+	}
+	if _, err := store.createFolder("Archive"); err != nil {
+		t.Fatalf("createFolder: %v", err)
+	}
+
+	if _, err := store.renameFolder(reports.ID, "Archive"); err != errFolderAlreadyExists {
+		t.Fatalf("expected errFolderAlreadyExists, got %v", err)
+	}
+	renamed, err := store.renameFolder(reports.ID, "Monthly reports")
+	if err != nil {
+		t.Fatalf("renameFolder: %v", err)
+	}
+	if renamed.Name != "Monthly reports" || renamed.ID == reports.ID {
+		t.Fatalf("expected the folder to be renamed with a new id, got %+v", renamed)
+	}
+	if _, err := store.renameFolder(reports.ID, "Other"); err != errFolderNotFound {
+		t.Fatalf("expected errFolderNotFound for the old id, got %v", err)
+	}
+}
+
+// racingStore saves a competing document before the first save it is given, as another
+// replica would.
+type racingStore struct {
+	state.Store
+	raced bool
+}
+
+func (s *racingStore) Save(ctx context.Context, doc state.Document) (int64, error) {
+	if !s.raced {
+		s.raced = true
+		other, _ := s.Store.Load(ctx)
+		other.Projects["default"] = state.Project{Name: "Renamed elsewhere", DiskSizeGB: 32}
+		if _, err := s.Store.Save(ctx, other); err != nil {
+			return 0, err
+		}
+	}
+	return s.Store.Save(ctx, doc)
+}
+
+func TestUpdateProjectReappliesChangeAfterConflict(t *testing.T) {
+	memory, _ := state.Open(context.Background(), state.Options{})
+	api := &API{store: &racingStore{Store: memory}, state: state.NewDocument(), stateLoaded: true}
+
+	calls := 0
+	err := api.updateProject(context.Background(), "default", func(project *state.Project) {
+		calls++
+		project.Name = "Renamed here"
+	})
+	if err != nil {
+		t.Fatalf("updateProject: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected the update to be reapplied once, got %d calls", calls)
+	}
+	saved := api.savedProject("default")
+	if saved.Name != "Renamed here" || saved.DiskSizeGB != 32 {
+		t.Fatalf("expected the change on top of the other replica's, got %+v", saved)
+	}
+	if stored, _ := memory.Load(context.Background()); stored.Revision != 2 || stored.Projects["default"].Name != "Renamed here" {
+		t.Fatalf("expected the store to hold the merged document, got %+v", stored)
+	}
+}
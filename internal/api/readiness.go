@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/lifecycle"
+	"github.com/Gouryella/supabase-studio-go/internal/metrics"
+	"github.com/Gouryella/supabase-studio-go/internal/tracing"
+)
+
+const (
+	componentOK       = "ok"
+	componentDown     = "down"
+	componentDisabled = "disabled"
+)
+
+// componentStatus is public, so failures are only logged: their errors name internal hosts.
+type componentStatus struct {
+	Status    string `json:"status"`
+	Required  bool   `json:"required"`
+	LatencyMS *int64 `json:"latency_ms,omitempty"`
+}
+
+type readinessProbe struct {
+	name     string
+	required bool
+	// check is nil when the component is not configured.
+	check func(r *http.Request) error
+}
+
+// NewReadinessHandler serves /readyz. It probes every configured upstream concurrently and
+// answers 503 when a required one is down or the server is draining; optional components
+// only degrade the status.
+func NewReadinessHandler(holder *config.Holder) http.Handler {
+	api := &API{holder: holder}
+	api.client = &http.Client{
+		Transport: metrics.NewTransport(tracing.NewTransport(http.DefaultTransport, api.upstreamName), api.upstreamName),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := api.config().ReadinessTimeout
+		if timeout <= 0 {
+			timeout = 2 * time.Second
+		}
+		if lifecycle.IsDraining(r.Context()) {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "draining", "components": map[string]componentStatus{}})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		probeRequest := r.WithContext(ctx)
+
+		probes := api.readinessProbes()
+		results := make(map[string]componentStatus, len(probes))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, probe := range probes {
+			if probe.check == nil {
+				mu.Lock()
+				results[probe.name] = componentStatus{Status: componentDisabled, Required: probe.required}
+				mu.Unlock()
+				continue
+			}
+			wg.Add(1)
+			go func(probe readinessProbe) {
+				defer wg.Done()
+				start := time.Now()
+				err := probe.check(probeRequest)
+				latency := time.Since(start).Milliseconds()
+				result := componentStatus{Status: componentOK, Required: probe.required, LatencyMS: &latency}
+				if err != nil {
+					result.Status = componentDown
+					slog.WarnContext(r.Context(), "readiness probe failed", "component", probe.name, "required", probe.required, "error", err)
+				}
+				mu.Lock()
+				results[probe.name] = result
+				mu.Unlock()
+			}(probe)
+		}
+		wg.Wait()
+
+		status, overall := http.StatusOK, "ok"
+		for _, result := range results {
+			if result.Status != componentDown {
+				continue
+			}
+			if result.Required {
+				status, overall = http.StatusServiceUnavailable, "unavailable"
+				break
+			}
+			overall = "degraded"
+		}
+		writeJSON(w, status, map[string]any{"status": overall, "components": results})
+	})
+}
+
+// readinessProbes checks the default project's services.
+func (api *API) readinessProbes() []readinessProbe {
+	project, _ := api.config().Project(config.DefaultProjectRef)
+	supabaseURL := strings.TrimSuffix(strings.TrimSpace(project.SupabaseURL), "/")
+	probes := []readinessProbe{
+		{name: "pg-meta", required: true},
+		{name: "auth", required: true},
+		{name: "storage", required: true},
+		{name: "rest", required: true},
+		{name: "logflare", required: false},
+	}
+
+	if api.config().PostgresDirect {
+		probes[0].name = "postgres"
+	}
+	if strings.TrimSpace(project.StudioPgMetaURL) != "" || api.config().PostgresDirect {
+		probes[0].check = func(r *http.Request) error {
+			_, pgErr, status, err := api.pgMetaExecute(r, "select 1", true)
+			if err != nil {
+				return err
+			}
+			if pgErr != nil {
+				return fmt.Errorf("%s", pgErr.Message)
+			}
+			if status >= 400 {
+				return fmt.Errorf("unexpected status %d", status)
+			}
+			return nil
+		}
+	}
+	if supabaseURL != "" {
+		probes[1].check = api.probeURL(authBaseURL(project)+"/health", authHeaders(project))
+		probes[2].check = api.probeURL(storageBaseURL(project)+"/status", storageHeaders(project))
+		probes[3].check = api.probeURL(supabaseURL+"/rest/v1/", authHeaders(project))
+	}
+	if logflareURL := strings.TrimSuffix(strings.TrimSpace(project.LogflareURL), "/"); logflareURL != "" {
+		probes[4].check = api.probeURL(logflareURL+"/health", http.Header{})
+	}
+	return probes
+}
+
+func (api *API) probeURL(target string, headers http.Header) func(r *http.Request) error {
+	return func(r *http.Request) error {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		req.Header = headers.Clone()
+		resp, err := api.client.Do(req)
+		if err != nil {
+			api.logUpstreamFailure(req, 0, nil, err)
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			api.logUpstreamFailure(req, resp.StatusCode, nil, nil)
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
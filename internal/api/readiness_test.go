@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/lifecycle"
+)
+
+func TestReadinessReportsComponents(t *testing.T) {
+	failing := map[string]bool{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing[r.URL.Path] {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/pg/query":
+			writeJSON(w, http.StatusOK, []map[string]any{{"?column?": 1}})
+		case "/auth/v1/health", "/storage/v1/status", "/rest/v1/", "/logflare/health":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := config.Config{
+		SupabaseURL:     upstream.URL,
+		StudioPgMetaURL: upstream.URL + "/pg",
+		PgMetaCryptoKey: "test-key",
+	}
+
+	check := func(cfg config.Config, wantStatus int, wantOverall string) map[string]componentStatus {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		NewReadinessHandler(config.NewHolder(cfg, "")).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != wantStatus {
+			t.Fatalf("expected %d, got %d: %s", wantStatus, rec.Code, rec.Body.String())
+		}
+		var payload struct {
+			Status     string                     `json:"status"`
+			Components map[string]componentStatus `json:"components"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode readiness: %v", err)
+		}
+		if payload.Status != wantOverall {
+			t.Fatalf("expected overall %q, got %q", wantOverall, payload.Status)
+		}
+		return payload.Components
+	}
+
+	components := check(cfg, http.StatusOK, "ok")
+	for _, name := range []string{"pg-meta", "auth", "storage", "rest"} {
+		if components[name].Status != componentOK || components[name].LatencyMS == nil {
+			t.Fatalf("expected %s to be ok with latency, got %+v", name, components[name])
+		}
+	}
+	if components["logflare"].Status != componentDisabled {
+		t.Fatalf("expected logflare to be disabled, got %+v", components["logflare"])
+	}
+
+	cfg.LogflareURL = upstream.URL + "/logflare"
+	failing["/logflare/health"] = true
+	if components := check(cfg, http.StatusOK, "degraded"); components["logflare"].Status != componentDown {
+		t.Fatalf("expected logflare to be down, got %+v", components["logflare"])
+	}
+
+	failing["/storage/v1/status"] = true
+	if components := check(cfg, http.StatusServiceUnavailable, "unavailable"); components["storage"].Status != componentDown {
+		t.Fatalf("expected storage to be down, got %+v", components["storage"])
+	}
+
+	rec := httptest.NewRecorder()
+	NewReadinessHandler(config.NewHolder(cfg, "")).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if strings.Contains(rec.Body.String(), strings.TrimPrefix(upstream.URL, "http://")) || strings.Contains(rec.Body.String(), "unexpected status") {
+		t.Fatalf("expected failure details to stay out of the public response, got %s", rec.Body.String())
+	}
+}
+
+func TestReadinessFailsWhileDraining(t *testing.T) {
+	drain := lifecycle.NewDrain()
+	drain.Start()
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	req = req.WithContext(lifecycle.WithDrain(req.Context(), drain))
+	rec := httptest.NewRecorder()
+	NewReadinessHandler(config.NewHolder(config.Config{}, "")).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"draining"`) {
+		t.Fatalf("expected draining 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
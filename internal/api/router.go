@@ -1,44 +1,202 @@
 package api
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Gouryella/supabase-studio-go/internal/audit"
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
 	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/metrics"
+	"github.com/Gouryella/supabase-studio-go/internal/ratelimit"
+	"github.com/Gouryella/supabase-studio-go/internal/state"
+	"github.com/Gouryella/supabase-studio-go/internal/tracing"
 	"github.com/go-chi/chi/v5"
 )
 
 type API struct {
-	cfg             config.Config
-	client          *http.Client
-	projectName     string
-	projectDiskSize int
-	stateFilePath   string
-	mu              sync.RWMutex
+	holder       *config.Holder
+	client       *http.Client
+	store        state.Store
+	state        state.Document
+	stateLoaded  bool
+	saveMu       sync.Mutex
+	postgres     postgresPools
+	audit        *audit.Logger
+	aiLimiter    *ratelimit.Limiter
+	queryLimiter *ratelimit.Limiter
+	aiBudget     *ratelimit.Budget
+	mu           sync.RWMutex
 }
 
-func NewRouter(cfg config.Config) http.Handler {
+// routePolicies lists the minimum role for routes that differ from the default, keyed by
+// method and route pattern without a trailing slash. Unlisted GET and HEAD routes need viewer, everything else developer.
+// Viewers still reach pg-meta, but always through the read-only Postgres user.
+var routePolicies = map[string]auth.Role{
+	"POST /platform/pg-meta/{ref}/query":                           auth.RoleViewer,
+	"POST /platform/storage/{ref}/buckets/{id}/objects/list":       auth.RoleViewer,
+	"POST /platform/storage/{ref}/buckets/{id}/objects/public-url": auth.RoleViewer,
+	"POST /platform/storage/{ref}/buckets/{id}/objects/download":   auth.RoleViewer,
+	"POST /platform/storage/{ref}/buckets/{id}/objects/sign":       auth.RoleViewer,
+	"POST /platform/telemetry/event":                               auth.RoleViewer,
+	"POST /ai/sql/generate-v4":                                     auth.RoleViewer,
+	"POST /ai/sql/policy":                                          auth.RoleViewer,
+	"POST /ai/sql/cron-v2":                                         auth.RoleViewer,
+	"POST /ai/sql/title-v2":                                        auth.RoleViewer,
+	"POST /ai/sql/filter-v1":                                       auth.RoleViewer,
+	"POST /ai/code/complete":                                       auth.RoleViewer,
+	"POST /ai/feedback/rate":                                       auth.RoleViewer,
+	"POST /ai/feedback/classify":                                   auth.RoleViewer,
+	"POST /ai/docs":                                                auth.RoleViewer,
+	"PATCH /platform/projects/{ref}":                               auth.RoleAdmin,
+	"POST /platform/projects/{ref}/resize":                         auth.RoleAdmin,
+	"POST /platform/projects/{ref}/disk":                           auth.RoleAdmin,
+	"PATCH /platform/projects/{ref}/config":                        auth.RoleAdmin,
+	"PATCH /platform/database/{ref}/pooling":                       auth.RoleAdmin,
+	"DELETE /platform/storage/{ref}/buckets/{id}":                  auth.RoleAdmin,
+	"DELETE /platform/auth/{ref}/users/{id}":                       auth.RoleAdmin,
+	"POST /platform/projects/{ref}/analytics/log-drains":           auth.RoleAdmin,
+	"PUT /platform/projects/{ref}/analytics/log-drains/{uuid}":     auth.RoleAdmin,
+	"DELETE /platform/projects/{ref}/analytics/log-drains/{uuid}":  auth.RoleAdmin,
+	"POST /integrations/stripe-sync":                               auth.RoleAdmin,
+	"DELETE /integrations/stripe-sync":                             auth.RoleAdmin,
+	"GET /platform/audit-log":                                      auth.RoleAdmin,
+	"POST /platform/config/reload":                                 auth.RoleAdmin,
+}
+
+func requiredRole(method, pattern string) auth.Role {
+	if role, ok := routePolicies[method+" "+strings.TrimSuffix(pattern, "/")]; ok {
+		return role
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return auth.RoleViewer
+	}
+	return auth.RoleDeveloper
+}
+
+// projectSecret returns secret, such as the service_role key or the JWT secret, when r may
+// see it. Either one bypasses row level security, so viewers get an empty value instead.
+func projectSecret(r *http.Request, secret string) string {
+	if !auth.RoleFromContext(r.Context()).Allows(auth.RoleDeveloper) {
+		return ""
+	}
+	return secret
+}
+
+// matchRoute resolves the route pattern and URL parameters of r before the router itself has run.
+func matchRoute(routes chi.Routes, r *http.Request) (*chi.Context, string) {
+	path := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		path = rctx.RoutePath
+	}
+	match := chi.NewRouteContext()
+	pattern := routes.Find(match, r.Method, path)
+	return match, pattern
+}
+
+// enforceRoutePolicy rejects requests whose user role is below the route's policy.
+func enforceRoutePolicy(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, pattern := matchRoute(routes, r)
+			if pattern == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			role := auth.RoleFromContext(r.Context())
+			if required := requiredRole(r.Method, pattern); !role.Allows(required) {
+				writeJSON(w, http.StatusForbidden, map[string]any{
+					"data":  nil,
+					"error": map[string]any{"message": "This action requires the " + string(required) + " role"},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// resolveProject rejects requests whose {ref} names no configured project.
+func (api *API) resolveProject(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			match, pattern := matchRoute(routes, r)
+			if pattern == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if ref := match.URLParam("ref"); ref != "" {
+				if _, ok := api.config().Project(ref); !ok {
+					writeJSON(w, http.StatusNotFound, map[string]any{
+						"data":  nil,
+						"error": map[string]any{"message": "Project " + ref + " not found"},
+					})
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// config returns the live configuration. Read it per request rather than keeping a copy, so
+// reloads and rotated *_FILE secrets take effect.
+func (api *API) config() config.Config {
+	return api.holder.Get()
+}
+
+// New opens the state store and keeps it, and the Postgres pools, until ctx is done.
+func New(ctx context.Context, holder *config.Holder) *API {
+	cfg := holder.Get()
 	api := &API{
-		cfg: cfg,
-		client: &http.Client{
-			Timeout: 120 * time.Second,
-		},
-		projectName:     cfg.DefaultProjectName,
-		projectDiskSize: cfg.DefaultProjectDiskSizeGB,
-		stateFilePath:   cfg.StateFilePath,
+		holder:       holder,
+		state:        state.NewDocument(),
+		audit:        newAuditLogger(cfg),
+		aiLimiter:    ratelimit.New(cfg.RateLimitAI),
+		queryLimiter: ratelimit.New(cfg.RateLimitQuery),
+		aiBudget:     ratelimit.NewBudget(int64(cfg.AIDailyTokens)),
+	}
+	api.client = &http.Client{
+		Timeout:   120 * time.Second,
+		Transport: metrics.NewTransport(tracing.NewTransport(http.DefaultTransport, api.upstreamName), api.upstreamName),
 	}
 
 	if err := api.ensureManagedFolders(); err != nil {
-		log.Printf("failed to create managed folders: %v", err)
+		slog.Error("failed to create managed folders", "error", err)
 	}
 
-	if err := api.loadStateFromDisk(); err != nil {
-		log.Printf("failed to load persisted supabase-studio-go state: %v", err)
+	store, err := OpenStateStore(cfg)
+	if err != nil {
+		slog.Error("failed to open the state store, dashboard settings cannot be saved", "backend", cfg.StateBackend, "error", err)
+		store = unavailableStore{err: err}
+	}
+	api.store = store
+	loadCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	if err := api.loadState(loadCtx); err != nil {
+		slog.Error("failed to load persisted supabase-studio-go state", "error", err)
 	}
+	cancel()
+	if watcher, ok := store.(state.Watcher); ok {
+		go watcher.Watch(ctx, api.reloadState)
+	}
+	go func() {
+		<-ctx.Done()
+		api.postgres.close()
+	}()
+	return api
+}
 
+// Router returns the API's routes, which the server mounts under /api.
+func (api *API) Router() http.Handler {
 	r := chi.NewRouter()
+	r.Use(api.recordAudit(r))
+	r.Use(enforceRoutePolicy(r))
+	r.Use(api.resolveProject(r))
 
 	r.Get("/get-ip-address", api.handleGetIPAddress)
 	r.Get("/get-utc-time", api.handleGetUTCTime)
@@ -53,16 +211,19 @@ func NewRouter(cfg config.Config) http.Handler {
 	r.Post("/mcp", api.handleMCP)
 	r.Route("/ai", func(r chi.Router) {
 		r.Get("/sql/check-api-key", api.handleCheckAPIKey)
-		r.Post("/sql/generate-v4", api.handleAISQLGenerateV4)
-		r.Post("/sql/policy", api.handleAISQLPolicy)
-		r.Post("/sql/cron-v2", api.handleAISQLCronV2)
-		r.Post("/sql/title-v2", api.handleAISQLTitleV2)
-		r.Post("/sql/filter-v1", api.handleAISQLFilterV1)
-		r.Post("/code/complete", api.handleAICodeComplete)
-		r.Post("/feedback/rate", api.handleAIFeedbackRate)
-		r.Post("/feedback/classify", api.handleAIFeedbackClassify)
-		r.Post("/docs", api.handleAIDocs)
-		r.Post("/onboarding/design", api.handleAIOnboardingDesign)
+		r.Group(func(r chi.Router) {
+			r.Use(ratelimit.Middleware(api.aiLimiter), api.aiBudget.Middleware)
+			r.Post("/sql/generate-v4", api.handleAISQLGenerateV4)
+			r.Post("/sql/policy", api.handleAISQLPolicy)
+			r.Post("/sql/cron-v2", api.handleAISQLCronV2)
+			r.Post("/sql/title-v2", api.handleAISQLTitleV2)
+			r.Post("/sql/filter-v1", api.handleAISQLFilterV1)
+			r.Post("/code/complete", api.handleAICodeComplete)
+			r.Post("/feedback/rate", api.handleAIFeedbackRate)
+			r.Post("/feedback/classify", api.handleAIFeedbackClassify)
+			r.Post("/docs", api.handleAIDocs)
+			r.Post("/onboarding/design", api.handleAIOnboardingDesign)
+		})
 	})
 	r.Route("/integrations", func(r chi.Router) {
 		r.MethodFunc("POST", "/stripe-sync", api.handleStripeSync)
@@ -82,7 +243,7 @@ func NewRouter(cfg config.Config) http.Handler {
 			r.Get("/materialized-views", api.pgMetaProxy("materialized-views"))
 			r.Get("/publications", api.pgMetaProxy("publications"))
 			r.Get("/triggers", api.pgMetaProxy("triggers"))
-			r.Post("/query", api.handlePgMetaQuery)
+			r.With(ratelimit.Middleware(api.queryLimiter)).Post("/query", api.handlePgMetaQuery)
 		})
 
 		r.Route("/storage/{ref}", func(r chi.Router) {
@@ -167,6 +328,7 @@ func NewRouter(cfg config.Config) http.Handler {
 						r.Post("/", api.handleSnippetFolders)
 						r.Route("/{id}", func(r chi.Router) {
 							r.Get("/", api.handleSnippetFolderByID)
+							r.Patch("/", api.handleSnippetFolderByID)
 							r.Delete("/", api.handleSnippetFolderByID)
 						})
 					})
@@ -201,6 +363,8 @@ func NewRouter(cfg config.Config) http.Handler {
 		})
 
 		r.Get("/profile", api.handleProfile)
+		r.Get("/audit-log", api.handleAuditLog)
+		r.Post("/config/reload", api.handleConfigReload)
 		r.Post("/telemetry/event", api.handleTelemetryEvent)
 	})
 
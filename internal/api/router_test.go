@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/go-chi/chi/v5"
+)
+
+// loginDisabled serves the API as the server does with dashboard authentication disabled.
+type loginDisabled struct {
+	*chi.Mux
+}
+
+func (h loginDisabled) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.Mux.ServeHTTP(w, r.WithContext(auth.WithoutLogin(r.Context())))
+}
+
+func newTestRouter(holder *config.Holder) http.Handler {
+	return loginDisabled{New(context.Background(), holder).Router().(*chi.Mux)}
+}
+
+func withRole(req *http.Request, role auth.Role) *http.Request {
+	return req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{Username: "tester", Role: role}))
+}
+
+func TestRoutePoliciesMatchRegisteredRoutes(t *testing.T) {
+	routes, ok := testAPIHandler().(chi.Routes)
+	if !ok {
+		t.Fatalf("expected NewRouter to return chi.Routes")
+	}
+
+	registered := map[string]bool{}
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		registered[method+" "+strings.TrimSuffix(route, "/")] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk routes: %v", err)
+	}
+
+	for key := range routePolicies {
+		if !registered[key] {
+			t.Errorf("route policy %q does not match a registered route", key)
+		}
+	}
+}
+
+func TestRoutePolicyBlocksViewersFromMutations(t *testing.T) {
+	handler := testAPIHandler()
+
+	cases := []struct {
+		method string
+		path   string
+		role   auth.Role
+	}{
+		{http.MethodDelete, "/platform/storage/default/buckets/avatars", auth.RoleViewer},
+		{http.MethodPut, "/platform/auth/default/users/1", auth.RoleViewer},
+		{http.MethodPost, "/v1/projects/default/database/migrations", auth.RoleViewer},
+		{http.MethodPatch, "/platform/projects/default", auth.RoleViewer},
+		{http.MethodPatch, "/platform/projects/default", auth.RoleDeveloper},
+		{http.MethodDelete, "/platform/storage/default/buckets/avatars", auth.RoleDeveloper},
+	}
+	for _, tc := range cases {
+		req := withRole(httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"name":"Renamed"}`)), tc.role)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected %s %s as %s to be forbidden, got %d", tc.method, tc.path, tc.role, rec.Code)
+		}
+	}
+}
+
+func TestRoutePolicyRejectsRequestsWithoutIdentity(t *testing.T) {
+	handler := testAPIHandler().(loginDisabled).Mux
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/platform/projects/default/settings", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a request without an identity to be forbidden, got %d", rec.Code)
+	}
+}
+
+func TestViewersCannotReadProjectSecrets(t *testing.T) {
+	handler := newTestRouter(config.NewHolder(config.Config{
+		SupabaseServiceKey: "service-role-secret",
+		AuthJWTSecret:      "jwt-signing-secret",
+	}, ""))
+	paths := []string{
+		"/platform/projects/default/settings",
+		"/v1/projects/default/api-keys",
+		"/platform/props/project/default/api",
+		"/platform/projects/default/config",
+		"/platform/projects/default/config/postgrest",
+	}
+
+	for _, path := range paths {
+		for _, role := range []auth.Role{auth.RoleViewer, auth.RoleDeveloper} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, withRole(httptest.NewRequest(http.MethodGet, path, nil), role))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected GET %s as %s to succeed, got %d", path, role, rec.Code)
+			}
+			body := rec.Body.String()
+			leaked := strings.Contains(body, "service-role-secret") || strings.Contains(body, "jwt-signing-secret")
+			if role == auth.RoleViewer && leaked {
+				t.Fatalf("expected GET %s to hide secrets from viewers, got %s", path, body)
+			}
+			if role == auth.RoleDeveloper && !leaked {
+				t.Fatalf("expected GET %s to return a secret to developers, got %s", path, body)
+			}
+		}
+	}
+}
+
+func TestRoutePolicyAllowsPrivilegedRoles(t *testing.T) {
+	handler := testAPIHandler()
+
+	req := withRole(httptest.NewRequest(http.MethodPatch, "/platform/projects/default", strings.NewReader(`{"name":"Renamed"}`)), auth.RoleAdmin)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected admin to update the project, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withRole(httptest.NewRequest(http.MethodGet, "/platform/projects/default", nil), auth.RoleViewer))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected viewer to read the project, got %d", rec.Code)
+	}
+}
+
+func TestViewerQueriesUseReadOnlyPostgresUser(t *testing.T) {
+	var connections []string
+	pgMeta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections = append(connections, decryptConnectionString(t, r.Header.Get("x-connection-encrypted"), "test-key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer pgMeta.Close()
+
+	handler := newTestRouter(config.NewHolder(config.Config{
+		StudioPgMetaURL:       pgMeta.URL,
+		PgMetaCryptoKey:       "test-key",
+		PostgresUserReadWrite: "supabase_admin",
+		PostgresUserReadOnly:  "supabase_read_only_user",
+	}, ""))
+
+	for _, role := range []auth.Role{auth.RoleViewer, auth.RoleDeveloper} {
+		req := withRole(httptest.NewRequest(http.MethodPost, "/platform/pg-meta/default/query", strings.NewReader(`{"query":"select 1"}`)), role)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected query as %s to succeed, got %d", role, rec.Code)
+		}
+	}
+
+	if len(connections) != 2 {
+		t.Fatalf("expected 2 pg-meta calls, got %d", len(connections))
+	}
+	if !strings.HasPrefix(connections[0], "postgresql://supabase_read_only_user:") {
+		t.Fatalf("expected viewer to use the read-only user, got %q", connections[0])
+	}
+	if !strings.HasPrefix(connections[1], "postgresql://supabase_admin:") {
+		t.Fatalf("expected developer to use the read-write user, got %q", connections[1])
+	}
+}
+
+func TestPgMetaDoesNotReceiveSessionCookies(t *testing.T) {
+	var cookie string
+	pgMeta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie = r.Header.Get("Cookie")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer pgMeta.Close()
+
+	handler := newTestRouter(config.NewHolder(config.Config{StudioPgMetaURL: pgMeta.URL, PgMetaCryptoKey: "test-key"}, ""))
+	req := withRole(httptest.NewRequest(http.MethodPost, "/platform/pg-meta/default/query", strings.NewReader(`{"query":"select 1"}`)), auth.RoleDeveloper)
+	req.Header.Set("Cookie", auth.SessionCookieName+"=session-jwt; theme=dark; supabase-studio-go-login=state")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if cookie != "theme=dark" {
+		t.Fatalf("expected only unrelated cookies to reach pg-meta, got %q", cookie)
+	}
+}
+
+func decryptConnectionString(t *testing.T, encrypted, passphrase string) string {
+	t.Helper()
+
+	raw, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(raw) < 16 || string(raw[:8]) != "Salted__" {
+		t.Fatalf("unexpected encrypted connection string %q", encrypted)
+	}
+	key, iv := evpBytesToKey([]byte(passphrase), raw[8:16], 32, 16)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("failed to create cipher: %v", err)
+	}
+	plain := make([]byte, len(raw)-16)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, raw[16:])
+	return string(plain[:len(plain)-int(plain[len(plain)-1])])
+}
+
+func TestAuditLogRecordsMutationsForAdmins(t *testing.T) {
+	pgMeta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer pgMeta.Close()
+
+	handler := newTestRouter(config.NewHolder(config.Config{
+		StudioPgMetaURL: pgMeta.URL,
+		PgMetaCryptoKey: "test-key",
+		AuditLogDir:     t.TempDir(),
+	}, ""))
+
+	queryReq := withRole(httptest.NewRequest(http.MethodPost, "/platform/pg-meta/default/query", strings.NewReader(`{"query":"drop table users"}`)), auth.RoleDeveloper)
+	handler.ServeHTTP(httptest.NewRecorder(), queryReq)
+	deniedReq := withRole(httptest.NewRequest(http.MethodDelete, "/platform/storage/default/buckets/avatars", nil), auth.RoleViewer)
+	handler.ServeHTTP(httptest.NewRecorder(), deniedReq)
+	handler.ServeHTTP(httptest.NewRecorder(), withRole(httptest.NewRequest(http.MethodGet, "/platform/projects/default", nil), auth.RoleViewer))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, withRole(httptest.NewRequest(http.MethodGet, "/platform/audit-log", nil), auth.RoleDeveloper))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected audit log to be admin only, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withRole(httptest.NewRequest(http.MethodGet, "/platform/audit-log?limit=10", nil), auth.RoleAdmin))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var page struct {
+		Entries []struct {
+			User    string            `json:"user"`
+			Method  string            `json:"method"`
+			Route   string            `json:"route"`
+			Target  string            `json:"target"`
+			Outcome string            `json:"outcome"`
+			Details map[string]string `json:"details"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to decode audit log: %v", err)
+	}
+	if len(page.Entries) != 2 {
+		t.Fatalf("expected 2 audited mutations, got %d: %s", len(page.Entries), rec.Body.String())
+	}
+
+	denied, query := page.Entries[0], page.Entries[1]
+	if denied.Outcome != "denied" || denied.Target != "ref=default id=avatars" {
+		t.Fatalf("unexpected denied entry %#v", denied)
+	}
+	if query.User != "tester" || query.Outcome != "success" || query.Details["query"] != "drop table users" {
+		t.Fatalf("unexpected query entry %#v", query)
+	}
+	if query.Route != "/platform/pg-meta/{ref}/query" {
+		t.Fatalf("expected route pattern to be recorded, got %q", query.Route)
+	}
+}
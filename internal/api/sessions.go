@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/state"
+)
+
+// sessionRevocations keeps logged-out dashboard sessions in the saved state, so they stay
+// revoked after a restart and, with the postgres backend, on every replica.
+type sessionRevocations struct {
+	api *API
+}
+
+// Revocations returns the store for the dashboard's logged-out sessions.
+func (api *API) Revocations() auth.Revocations {
+	return sessionRevocations{api: api}
+}
+
+func (s sessionRevocations) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	now := time.Now()
+	return s.api.updateState(ctx, func(doc *state.Document) {
+		if doc.RevokedSessions == nil {
+			doc.RevokedSessions = map[string]time.Time{}
+		}
+		maps.DeleteFunc(doc.RevokedSessions, func(_ string, expires time.Time) bool {
+			return expires.Before(now)
+		})
+		doc.RevokedSessions[id] = expiresAt.UTC()
+	})
+}
+
+func (s sessionRevocations) Revoked(id string) bool {
+	s.api.mu.RLock()
+	defer s.api.mu.RUnlock()
+	_, revoked := s.api.state.RevokedSessions[id]
+	return revoked
+}
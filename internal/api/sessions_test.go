@@ -0,0 +1,34 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+func TestSessionRevocationsSurviveARestart(t *testing.T) {
+	holder := config.NewHolder(config.Config{
+		StateFilePath: filepath.Join(t.TempDir(), "supabase-studio-go-state.json"),
+	}, "")
+
+	revocations := New(t.Context(), holder).Revocations()
+	if err := revocations.Revoke(t.Context(), "expired", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := revocations.Revoke(t.Context(), "logged-out", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+
+	restarted := New(t.Context(), holder)
+	if !restarted.Revocations().Revoked("logged-out") {
+		t.Fatal("expected the logged-out session to stay revoked after a restart")
+	}
+	if restarted.Revocations().Revoked("active") {
+		t.Fatal("expected other sessions to be accepted")
+	}
+	if _, kept := restarted.state.RevokedSessions["expired"]; kept {
+		t.Fatalf("expected expired revocations to be dropped, got %v", restarted.state.RevokedSessions)
+	}
+}
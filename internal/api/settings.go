@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"github.com/Gouryella/supabase-studio-go/internal/state"
+)
+
+type settingKind int
+
+const (
+	settingString settingKind = iota
+	settingNumber
+	settingNullableNumber
+)
+
+// postgrestSettings and poolingSettings are the settings screens' fields that are saved.
+// Anything else in a PATCH body is ignored.
+var (
+	postgrestSettings = map[string]settingKind{
+		"db_schema":            settingString,
+		"db_extra_search_path": settingString,
+		"max_rows":             settingNumber,
+		"db_pool":              settingNullableNumber,
+	}
+	poolingSettings = map[string]settingKind{
+		"pool_mode":         settingString,
+		"default_pool_size": settingNumber,
+		"max_client_conn":   settingNumber,
+	}
+)
+
+func defaultPostgrestConfig() map[string]any {
+	return map[string]any{
+		"db_anon_role":         "anon",
+		"db_extra_search_path": "public",
+		"db_schema":            "public, storage",
+		"max_rows":             100,
+		"role_claim_key":       ".role",
+	}
+}
+
+func defaultPoolingConfig() map[string]any {
+	return map[string]any{
+		"db_port":           6543,
+		"pool_mode":         "transaction",
+		"pgbouncer_enabled": true,
+		"pgbouncer_status":  "COMING_UP",
+	}
+}
+
+// mergeSettings returns defaults overridden by the saved settings.
+func mergeSettings(defaults, saved map[string]any) map[string]any {
+	merged := maps.Clone(defaults)
+	maps.Copy(merged, saved)
+	return merged
+}
+
+// settingsUpdate returns the fields of payload listed in schema, checking their types.
+func settingsUpdate(schema map[string]settingKind, payload map[string]any) (map[string]any, error) {
+	update := map[string]any{}
+	for key, value := range payload {
+		kind, ok := schema[key]
+		if !ok {
+			continue
+		}
+		switch value.(type) {
+		case string:
+			if kind != settingString {
+				return nil, fmt.Errorf("%s must be a number", key)
+			}
+		case float64:
+			if kind == settingString {
+				return nil, fmt.Errorf("%s must be a string", key)
+			}
+		case nil:
+			if kind != settingNullableNumber {
+				return nil, fmt.Errorf("%s cannot be null", key)
+			}
+		default:
+			return nil, fmt.Errorf("%s has an unsupported type", key)
+		}
+		update[key] = value
+	}
+	return update, nil
+}
+
+// patchSettings saves the fields of the request body listed in schema into the saved
+// settings picked by field, and returns the saved settings after the update.
+func (api *API) patchSettings(w http.ResponseWriter, r *http.Request, schema map[string]settingKind, field func(*state.Project) *map[string]any, validate func(map[string]any) error) (map[string]any, bool) {
+	var payload map[string]any
+	if err := decodeJSON(r, &payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"message": "Invalid request body"}})
+		return nil, false
+	}
+	update, err := settingsUpdate(schema, payload)
+	if err == nil && validate != nil {
+		err = validate(update)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"message": err.Error()}})
+		return nil, false
+	}
+
+	var saved map[string]any
+	err = api.updateProject(r.Context(), api.project(r).Ref, func(project *state.Project) {
+		settings := field(project)
+		if *settings == nil {
+			*settings = map[string]any{}
+		}
+		maps.Copy(*settings, update)
+		saved = *settings
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"error": map[string]any{"message": "Failed to persist project settings"},
+		})
+		return nil, false
+	}
+	return saved, true
+}
+
+func validatePooling(update map[string]any) error {
+	if mode, ok := update["pool_mode"]; ok && mode != "transaction" && mode != "session" {
+		return errors.New("pool_mode must be transaction or session")
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 	"io/fs"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -68,18 +69,31 @@ var (
 	errSnippetsFolderEnvNotSet     = errors.New("snippets management folder env var (SNIPPETS_MANAGEMENT_FOLDER) is not set; set it to use snippets properly")
 )
 
-func (api *API) snippetsDir() (string, error) {
-	if api.cfg.SnippetsFolder == "" {
+// snippetStore keeps one project's snippets as .sql files in its management folder, with
+// one level of subfolders.
+type snippetStore struct {
+	folder    string
+	projectID int
+}
+
+// snippetsFor returns the snippet store of the project named by the request's {ref}.
+func (api *API) snippetsFor(r *http.Request) snippetStore {
+	project := api.project(r)
+	return snippetStore{folder: project.SnippetsFolder, projectID: api.projectID(project.Ref)}
+}
+
+func (s snippetStore) snippetsDir() (string, error) {
+	if s.folder == "" {
 		return "", errSnippetsFolderEnvNotSet
 	}
-	if err := os.MkdirAll(api.cfg.SnippetsFolder, 0o755); err != nil {
+	if err := os.MkdirAll(s.folder, 0o755); err != nil {
 		return "", err
 	}
-	return api.cfg.SnippetsFolder, nil
+	return s.folder, nil
 }
 
-func (api *API) getFilesystemEntries() ([]filesystemEntry, error) {
-	root, err := api.snippetsDir()
+func (s snippetStore) getFilesystemEntries() ([]filesystemEntry, error) {
+	root, err := s.snippetsDir()
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +172,7 @@ func (api *API) getFilesystemEntries() ([]filesystemEntry, error) {
 	return entries, nil
 }
 
-func buildSnippet(name, content string, folderID *string, createdAt time.Time) snippet {
+func (s snippetStore) buildSnippet(name, content string, folderID *string, createdAt time.Time) snippet {
 	idInputs := []string{name + ".sql"}
 	if folderID != nil {
 		idInputs = []string{*folderID, name + ".sql"}
@@ -177,7 +191,7 @@ func buildSnippet(name, content string, folderID *string, createdAt time.Time) s
 			SchemaVersion: "1.0",
 		},
 		Visibility: "user",
-		ProjectID:  1,
+		ProjectID:  s.projectID,
 		FolderID:   folderID,
 		OwnerID:    1,
 		Owner:      snippetUser{ID: 1, Username: "johndoe"},
@@ -185,8 +199,8 @@ func buildSnippet(name, content string, folderID *string, createdAt time.Time) s
 	}
 }
 
-func (api *API) getSnippets(searchTerm string, limit int, cursor string, sortField string, sortOrder string, folderID *string) (string, []snippet, error) {
-	entries, err := api.getFilesystemEntries()
+func (s snippetStore) getSnippets(searchTerm string, limit int, cursor string, sortField string, sortOrder string, folderID *string) (string, []snippet, error) {
+	entries, err := s.getFilesystemEntries()
 	if err != nil {
 		return "", nil, err
 	}
@@ -251,13 +265,13 @@ func (api *API) getSnippets(searchTerm string, limit int, cursor string, sortFie
 
 	snippets := make([]snippet, 0, len(filtered))
 	for _, entry := range filtered {
-		snippets = append(snippets, buildSnippet(entry.Name, entry.Content, entry.FolderID, entry.CreatedAt))
+		snippets = append(snippets, s.buildSnippet(entry.Name, entry.Content, entry.FolderID, entry.CreatedAt))
 	}
 	return nextCursor, snippets, nil
 }
 
-func (api *API) saveSnippet(newSnippet snippet) (snippet, error) {
-	entries, err := api.getFilesystemEntries()
+func (s snippetStore) saveSnippet(newSnippet snippet) (snippet, error) {
+	entries, err := s.getFilesystemEntries()
 	if err != nil {
 		return snippet{}, err
 	}
@@ -283,7 +297,7 @@ func (api *API) saveSnippet(newSnippet snippet) (snippet, error) {
 
 	name := sanitizeName(newSnippet.Name)
 	content := newSnippet.Content.SQL
-	root, err := api.snippetsDir()
+	root, err := s.snippetsDir()
 	if err != nil {
 		return snippet{}, err
 	}
@@ -306,11 +320,11 @@ func (api *API) saveSnippet(newSnippet snippet) (snippet, error) {
 	if err != nil {
 		return snippet{}, err
 	}
-	return buildSnippet(name, content, newSnippet.FolderID, info.ModTime()), nil
+	return s.buildSnippet(name, content, newSnippet.FolderID, info.ModTime()), nil
 }
 
-func (api *API) deleteSnippet(id string) error {
-	entries, err := api.getFilesystemEntries()
+func (s snippetStore) deleteSnippet(id string) error {
+	entries, err := s.getFilesystemEntries()
 	if err != nil {
 		return err
 	}
@@ -325,7 +339,7 @@ func (api *API) deleteSnippet(id string) error {
 		return errSnippetNotFound
 	}
 
-	root, err := api.snippetsDir()
+	root, err := s.snippetsDir()
 	if err != nil {
 		return err
 	}
@@ -346,8 +360,8 @@ func (api *API) deleteSnippet(id string) error {
 	return nil
 }
 
-func (api *API) updateSnippet(id string, updates map[string]any) (snippet, error) {
-	entries, err := api.getFilesystemEntries()
+func (s snippetStore) updateSnippet(id string, updates map[string]any) (snippet, error) {
+	entries, err := s.getFilesystemEntries()
 	if err != nil {
 		return snippet{}, err
 	}
@@ -397,7 +411,7 @@ func (api *API) updateSnippet(id string, updates map[string]any) (snippet, error
 		}
 	}
 
-	if err := api.deleteSnippet(found.ID); err != nil {
+	if err := s.deleteSnippet(found.ID); err != nil {
 		return snippet{}, err
 	}
 
@@ -407,11 +421,11 @@ func (api *API) updateSnippet(id string, updates map[string]any) (snippet, error
 		Content:  snippetContent{SQL: content, ContentID: uuid.NewString(), SchemaVersion: "1.0"},
 		FolderID: folderID,
 	}
-	return api.saveSnippet(updatedSnippet)
+	return s.saveSnippet(updatedSnippet)
 }
 
-func (api *API) getFolders(folderID *string) ([]folder, error) {
-	entries, err := api.getFilesystemEntries()
+func (s snippetStore) getFolders(folderID *string) ([]folder, error) {
+	entries, err := s.getFilesystemEntries()
 	if err != nil {
 		return nil, err
 	}
@@ -423,15 +437,15 @@ func (api *API) getFolders(folderID *string) ([]folder, error) {
 				Name:      entry.Name,
 				OwnerID:   1,
 				ParentID:  nil,
-				ProjectID: 1,
+				ProjectID: s.projectID,
 			})
 		}
 	}
 	return folders, nil
 }
 
-func (api *API) createFolder(name string) (folder, error) {
-	root, err := api.snippetsDir()
+func (s snippetStore) createFolder(name string) (folder, error) {
+	root, err := s.snippetsDir()
 	if err != nil {
 		return folder{}, err
 	}
@@ -440,7 +454,7 @@ func (api *API) createFolder(name string) (folder, error) {
 		return folder{}, errFolderNameRequired
 	}
 
-	entries, _ := api.getFilesystemEntries()
+	entries, _ := s.getFilesystemEntries()
 	for _, entry := range entries {
 		if entry.Type == "folder" && entry.Name == name {
 			return folder{}, errFolderAlreadyExists
@@ -457,12 +471,12 @@ func (api *API) createFolder(name string) (folder, error) {
 		Name:      name,
 		OwnerID:   1,
 		ParentID:  nil,
-		ProjectID: 1,
+		ProjectID: s.projectID,
 	}, nil
 }
 
-func (api *API) deleteFolder(id string) error {
-	entries, err := api.getFilesystemEntries()
+func (s snippetStore) deleteFolder(id string) error {
+	entries, err := s.getFilesystemEntries()
 	if err != nil {
 		return err
 	}
@@ -476,7 +490,7 @@ func (api *API) deleteFolder(id string) error {
 	if target == nil {
 		return errFolderNotFound
 	}
-	root, err := api.snippetsDir()
+	root, err := s.snippetsDir()
 	if err != nil {
 		return err
 	}
@@ -484,14 +498,54 @@ func (api *API) deleteFolder(id string) error {
 	return os.RemoveAll(folderPath)
 }
 
-func (api *API) getSnippet(id string) (snippet, error) {
-	entries, err := api.getFilesystemEntries()
+func (s snippetStore) renameFolder(id, name string) (folder, error) {
+	name = sanitizeName(name)
+	if name == "" {
+		return folder{}, errFolderNameRequired
+	}
+	entries, err := s.getFilesystemEntries()
+	if err != nil {
+		return folder{}, err
+	}
+	var target *filesystemEntry
+	for _, entry := range entries {
+		if entry.Type != "folder" {
+			continue
+		}
+		if entry.Name == name && entry.ID != id {
+			return folder{}, errFolderAlreadyExists
+		}
+		if entry.ID == id {
+			target = &entry
+		}
+	}
+	if target == nil {
+		return folder{}, errFolderNotFound
+	}
+	root, err := s.snippetsDir()
+	if err != nil {
+		return folder{}, err
+	}
+	if err := os.Rename(filepath.Join(root, target.Name), filepath.Join(root, name)); err != nil {
+		return folder{}, err
+	}
+	return folder{
+		ID:        deterministicUUID([]string{name}),
+		Name:      name,
+		OwnerID:   1,
+		ParentID:  nil,
+		ProjectID: s.projectID,
+	}, nil
+}
+
+func (s snippetStore) getSnippet(id string) (snippet, error) {
+	entries, err := s.getFilesystemEntries()
 	if err != nil {
 		return snippet{}, err
 	}
 	for _, entry := range entries {
 		if entry.Type == "file" && entry.ID == id {
-			return buildSnippet(entry.Name, entry.Content, entry.FolderID, entry.CreatedAt), nil
+			return s.buildSnippet(entry.Name, entry.Content, entry.FolderID, entry.CreatedAt), nil
 		}
 	}
 	return snippet{}, errSnippetNotFound
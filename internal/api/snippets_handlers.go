@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -39,7 +40,7 @@ func (api *API) handleSnippetsGet(w http.ResponseWriter, r *http.Request) {
 		sortOrder = "desc"
 	}
 
-	nextCursor, snippets, err := api.getSnippets(r.URL.Query().Get("name"), limit, cursor, sortBy, sortOrder, nil)
+	nextCursor, snippets, err := api.snippetsFor(r).getSnippets(r.URL.Query().Get("name"), limit, cursor, sortBy, sortOrder, nil)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"data": []any{}})
 		return
@@ -59,7 +60,7 @@ func (api *API) handleSnippetsPut(w http.ResponseWriter, r *http.Request) {
 	}
 
 	id, _ := payload["id"].(string)
-	updated, err := api.updateSnippet(id, payload)
+	updated, err := api.snippetsFor(r).updateSnippet(id, payload)
 	if err == nil {
 		writeJSON(w, http.StatusOK, updated)
 		return
@@ -87,7 +88,7 @@ func (api *API) handleSnippetsPut(w http.ResponseWriter, r *http.Request) {
 		},
 		FolderID: folderID,
 	}
-	saved, err := api.saveSnippet(newSnippet)
+	saved, err := api.snippetsFor(r).saveSnippet(newSnippet)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Failed to create snippet"})
 		return
@@ -108,7 +109,7 @@ func (api *API) handleSnippetsDelete(w http.ResponseWriter, r *http.Request) {
 		if id == "" {
 			continue
 		}
-		if err := api.deleteSnippet(id); err != nil {
+		if err := api.snippetsFor(r).deleteSnippet(id); err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "Failed to delete snippets"})
 			return
 		}
@@ -119,7 +120,7 @@ func (api *API) handleSnippetsDelete(w http.ResponseWriter, r *http.Request) {
 
 func (api *API) handleSnippetCount(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
-	_, snippets, err := api.getSnippets(name, 0, "", "", "desc", nil)
+	_, snippets, err := api.snippetsFor(r).getSnippets(name, 0, "", "", "desc", nil)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"message": "Failed to get count"})
 		return
@@ -153,12 +154,12 @@ func (api *API) handleSnippetCount(w http.ResponseWriter, r *http.Request) {
 func (api *API) handleSnippetFolders(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		folders, err := api.getFolders(nil)
+		folders, err := api.snippetsFor(r).getFolders(nil)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
 			return
 		}
-		cursor, snippets, err := api.getSnippets(r.URL.Query().Get("name"), parseLimit(r), r.URL.Query().Get("cursor"), r.URL.Query().Get("sort_by"), r.URL.Query().Get("sort_order"), nil)
+		cursor, snippets, err := api.snippetsFor(r).getSnippets(r.URL.Query().Get("name"), parseLimit(r), r.URL.Query().Get("cursor"), r.URL.Query().Get("sort_by"), r.URL.Query().Get("sort_order"), nil)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
 			return
@@ -175,7 +176,7 @@ func (api *API) handleSnippetFolders(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		name, _ := payload["name"].(string)
-		folder, err := api.createFolder(name)
+		folder, err := api.snippetsFor(r).createFolder(name)
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 			return
@@ -192,7 +193,7 @@ func (api *API) handleSnippetFolders(w http.ResponseWriter, r *http.Request) {
 			if id == "" {
 				continue
 			}
-			if err := api.deleteFolder(id); err != nil {
+			if err := api.snippetsFor(r).deleteFolder(id); err != nil {
 				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
 				return
 			}
@@ -205,7 +206,23 @@ func (api *API) handleSnippetFolders(w http.ResponseWriter, r *http.Request) {
 
 func (api *API) handleSnippetFolderByID(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPatch {
-		writeJSON(w, http.StatusOK, map[string]any{})
+		var payload map[string]any
+		if err := decodeJSON(r, &payload); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid request body"})
+			return
+		}
+		name, _ := payload["name"].(string)
+		folder, err := api.snippetsFor(r).renameFolder(chiURLParam(r, "id"), name)
+		switch {
+		case errors.Is(err, errFolderNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+		case errors.Is(err, errFolderNameRequired), errors.Is(err, errFolderAlreadyExists):
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusOK, folder)
+		}
 		return
 	}
 	if r.Method != http.MethodGet {
@@ -213,12 +230,12 @@ func (api *API) handleSnippetFolderByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	folderID := chiURLParam(r, "id")
-	folders, err := api.getFolders(&folderID)
+	folders, err := api.snippetsFor(r).getFolders(&folderID)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
 		return
 	}
-	cursor, snippets, err := api.getSnippets(r.URL.Query().Get("name"), parseLimit(r), r.URL.Query().Get("cursor"), r.URL.Query().Get("sort_by"), r.URL.Query().Get("sort_order"), &folderID)
+	cursor, snippets, err := api.snippetsFor(r).getSnippets(r.URL.Query().Get("name"), parseLimit(r), r.URL.Query().Get("cursor"), r.URL.Query().Get("sort_by"), r.URL.Query().Get("sort_order"), &folderID)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"message": err.Error()})
 		return
@@ -236,7 +253,7 @@ func (api *API) handleSnippetItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chiURLParam(r, "id")
-	snippet, err := api.getSnippet(id)
+	snippet, err := api.snippetsFor(r).getSnippet(id)
 	if err != nil {
 		if strings.Contains(strings.ToLower(err.Error()), "not found") {
 			writeJSON(w, http.StatusNotFound, map[string]any{"message": "Content not found."})
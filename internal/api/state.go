@@ -1,99 +1,146 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"os"
-	"path/filepath"
-	"strings"
-)
+	"log/slog"
+	"time"
 
-type persistedState struct {
-	ProjectName       string `json:"project_name"`
-	ProjectDiskSizeGB int    `json:"project_disk_size_gb"`
-}
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/state"
+)
 
-func (api *API) loadStateFromDisk() error {
-	if strings.TrimSpace(api.stateFilePath) == "" {
-		return nil
+// stateDatabaseURL is the Postgres state backend's database: the configured URL, or the
+// default project's database as its read-write user.
+func stateDatabaseURL(cfg config.Config) string {
+	if cfg.StateDatabaseURL != "" {
+		return cfg.StateDatabaseURL
 	}
+	project, _ := cfg.Project(config.DefaultProjectRef)
+	return postgresURL(project, false)
+}
 
-	bytes, err := os.ReadFile(api.stateFilePath)
+// OpenStateStore opens the store configured for the dashboard's saved settings.
+func OpenStateStore(cfg config.Config) (state.Store, error) {
+	keyring, err := state.LoadKeyring(cfg.StateKeyFile, cfg.StatePreviousKeyFiles)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil
-		}
-		return err
+		return nil, err
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return state.Open(ctx, state.Options{
+		Backend:     cfg.StateBackend,
+		FilePath:    cfg.StateFilePath,
+		DatabaseURL: stateDatabaseURL(cfg),
+		Keyring:     keyring,
+	})
+}
 
-	var state persistedState
-	if err := json.Unmarshal(bytes, &state); err != nil {
+// maxSaveAttempts bounds how often updateState reloads and reapplies a change that lost
+// a race with another replica.
+const maxSaveAttempts = 5
+
+// loadState replaces the cached document with the store's, unless the cache already holds
+// a later revision.
+func (api *API) loadState(ctx context.Context) error {
+	doc, err := api.store.Load(ctx)
+	if err != nil {
 		return err
 	}
+	api.cacheState(doc)
+	return nil
+}
 
-	name := strings.TrimSpace(state.ProjectName)
-	if name == "" {
-		name = api.cfg.DefaultProjectName
+func (api *API) cacheState(doc state.Document) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if api.stateLoaded && doc.Revision < api.state.Revision {
+		return
 	}
-	api.setProjectName(name)
+	api.state = doc
+	api.stateLoaded = true
+}
 
-	if state.ProjectDiskSizeGB > 0 {
-		api.setProjectDiskSize(state.ProjectDiskSizeGB)
+// reloadState is called when another replica may have saved the document.
+func (api *API) reloadState() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := api.loadState(ctx); err != nil {
+		slog.Error("failed to reload supabase-studio-go state", "error", err)
 	}
+}
 
-	return nil
+// savedProject returns the settings saved for ref.
+func (api *API) savedProject(ref string) state.Project {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+	return api.state.Projects[ref]
 }
 
-func (api *API) persistStateToDisk() error {
-	if strings.TrimSpace(api.stateFilePath) == "" {
-		return nil
-	}
+// updateProject applies update to the settings saved for ref and saves the document, as
+// updateState does.
+func (api *API) updateProject(ctx context.Context, ref string, update func(*state.Project)) error {
+	return api.updateState(ctx, func(doc *state.Document) {
+		project := doc.Projects[ref]
+		update(&project)
+		doc.Projects[ref] = project
+	})
+}
 
-	dir := filepath.Dir(api.stateFilePath)
-	if dir != "" && dir != "." {
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+// updateState applies update to the document and saves it. The cached document only
+// changes once the store has accepted the new one. A document that could not be loaded at
+// startup is loaded first, so that saving never drops settings. When another replica saved
+// in between, the document is reloaded and update applied again, so update may run more
+// than once.
+func (api *API) updateState(ctx context.Context, update func(*state.Document)) error {
+	api.saveMu.Lock()
+	defer api.saveMu.Unlock()
+
+	api.mu.RLock()
+	loaded := api.stateLoaded
+	api.mu.RUnlock()
+	if !loaded {
+		if err := api.loadState(ctx); err != nil {
 			return err
 		}
 	}
 
-	payload := persistedState{
-		ProjectName:       api.getProjectName(),
-		ProjectDiskSizeGB: api.getProjectDiskSize(),
-	}
+	var err error
+	for range maxSaveAttempts {
+		api.mu.RLock()
+		next := api.state.Clone()
+		api.mu.RUnlock()
 
-	bytes, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	tmpPath := api.stateFilePath + ".tmp"
-	if err := os.WriteFile(tmpPath, bytes, 0o644); err != nil {
-		return err
+		update(&next)
+		next.Revision, err = api.store.Save(ctx, next)
+		if err == nil {
+			api.cacheState(next)
+			return nil
+		}
+		if !errors.Is(err, state.ErrConflict) {
+			return err
+		}
+		if err := api.loadState(ctx); err != nil {
+			return err
+		}
 	}
-
-	return os.Rename(tmpPath, api.stateFilePath)
+	return err
 }
 
-func (api *API) updateProjectName(name string) error {
-	previous := api.getProjectName()
-	api.setProjectName(name)
-
-	if err := api.persistStateToDisk(); err != nil {
-		api.setProjectName(previous)
-		return err
-	}
-
-	return nil
+// unavailableStore stands in for a store that could not be opened, so that saving fails
+// instead of silently dropping settings.
+type unavailableStore struct {
+	err error
 }
 
-func (api *API) updateProjectDiskSize(size int) error {
-	previous := api.getProjectDiskSize()
-	api.setProjectDiskSize(size)
+func (s unavailableStore) Load(context.Context) (state.Document, error) {
+	return state.Document{}, s.err
+}
 
-	if err := api.persistStateToDisk(); err != nil {
-		api.setProjectDiskSize(previous)
-		return err
-	}
+func (s unavailableStore) Save(context.Context, state.Document) (int64, error) {
+	return 0, s.err
+}
 
+func (s unavailableStore) Close() error {
 	return nil
 }
@@ -7,17 +7,20 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/audit"
+	"github.com/Gouryella/supabase-studio-go/internal/config"
 )
 
-func (api *API) storageBaseURL() string {
-	return strings.TrimSuffix(api.cfg.SupabaseURL, "/") + "/storage/v1"
+func storageBaseURL(project config.Project) string {
+	return strings.TrimSuffix(project.SupabaseURL, "/") + "/storage/v1"
 }
 
-func (api *API) storageHeaders() http.Header {
+func storageHeaders(project config.Project) http.Header {
 	headers := http.Header{}
-	if api.cfg.SupabaseServiceKey != "" {
-		headers.Set("apikey", api.cfg.SupabaseServiceKey)
-		headers.Set("Authorization", "Bearer "+api.cfg.SupabaseServiceKey)
+	if serviceKey := project.SupabaseServiceKey; serviceKey != "" {
+		headers.Set("apikey", serviceKey)
+		headers.Set("Authorization", "Bearer "+serviceKey)
 	}
 	headers.Set("Content-Type", "application/json")
 	return headers
@@ -26,11 +29,11 @@ func (api *API) storageHeaders() http.Header {
 func (api *API) handleStorageBuckets(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		api.storageProxy(w, r, http.MethodGet, api.storageBaseURL()+"/bucket", nil)
+		api.storageProxy(w, r, http.MethodGet, storageBaseURL(api.project(r))+"/bucket", nil)
 	case http.MethodPost:
 		body, _ := readRawBody(r)
 		normalizedBody := normalizeStorageCreateBucketBody(body)
-		api.storageProxy(w, r, http.MethodPost, api.storageBaseURL()+"/bucket", normalizedBody)
+		api.storageProxy(w, r, http.MethodPost, storageBaseURL(api.project(r))+"/bucket", normalizedBody)
 	default:
 		writeMethodNotAllowed(w, r, "GET, POST")
 	}
@@ -71,7 +74,7 @@ func (api *API) handleStorageBucket(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"message": "Bucket ID is required"}})
 		return
 	}
-	target := api.storageBaseURL() + "/bucket/" + url.PathEscape(bucket)
+	target := storageBaseURL(api.project(r)) + "/bucket/" + url.PathEscape(bucket)
 
 	switch r.Method {
 	case http.MethodGet:
@@ -116,7 +119,7 @@ func (api *API) handleStorageEmptyBucket(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	bucket := chiURLParam(r, "id")
-	target := api.storageBaseURL() + "/bucket/" + url.PathEscape(bucket) + "/empty"
+	target := storageBaseURL(api.project(r)) + "/bucket/" + url.PathEscape(bucket) + "/empty"
 	api.storageProxy(w, r, http.MethodPost, target, nil)
 }
 
@@ -146,7 +149,7 @@ func (api *API) handleStorageObjectsList(w http.ResponseWriter, r *http.Request)
 		bodyMap[k] = v
 	}
 	bodyBytes, _ := json.Marshal(bodyMap)
-	target := api.storageBaseURL() + "/object/list/" + url.PathEscape(bucket)
+	target := storageBaseURL(api.project(r)) + "/object/list/" + url.PathEscape(bucket)
 	api.storageProxy(w, r, http.MethodPost, target, bodyBytes)
 }
 
@@ -160,10 +163,11 @@ func (api *API) handleStorageObjectsDelete(w http.ResponseWriter, r *http.Reques
 		Paths []string `json:"paths"`
 	}
 	_ = decodeJSON(r, &payload)
+	audit.Annotate(r.Context(), "paths", strings.Join(payload.Paths, ", "))
 	bodyBytes, _ := json.Marshal(map[string]any{
 		"prefixes": payload.Paths,
 	})
-	target := api.storageBaseURL() + "/object/" + url.PathEscape(bucket)
+	target := storageBaseURL(api.project(r)) + "/object/" + url.PathEscape(bucket)
 	api.storageProxy(w, r, http.MethodDelete, target, bodyBytes)
 }
 
@@ -178,9 +182,10 @@ func (api *API) handleStorageObjectsPublicURL(w http.ResponseWriter, r *http.Req
 	}
 	_ = decodeJSON(r, &payload)
 
-	publicBase := api.cfg.SupabasePublicURL
+	project := api.project(r)
+	publicBase := project.SupabasePublicURL
 	if publicBase == "" {
-		publicBase = api.cfg.SupabaseURL
+		publicBase = project.SupabaseURL
 	}
 	publicURL := strings.TrimSuffix(publicBase, "/") + "/storage/v1/object/public/" + url.PathEscape(bucket) + "/" + strings.TrimPrefix(payload.Path, "/")
 
@@ -213,7 +218,7 @@ func (api *API) handleStorageObjectsSign(w http.ResponseWriter, r *http.Request)
 		bodyMap["transform"] = transform
 	}
 	bodyBytes, _ := json.Marshal(bodyMap)
-	target := api.storageBaseURL() + "/object/sign/" + url.PathEscape(bucket) + "/" + escapeStorageObjectPath(payload.Path)
+	target := storageBaseURL(api.project(r)) + "/object/sign/" + url.PathEscape(bucket) + "/" + escapeStorageObjectPath(payload.Path)
 	respBody, status, err := api.storageRaw(r, http.MethodPost, target, bodyBytes)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": err.Error()}})
@@ -233,7 +238,7 @@ func (api *API) handleStorageObjectsSign(w http.ResponseWriter, r *http.Request)
 		signedURL, _ = response["signedURL"].(string)
 	}
 	if signedURL != "" {
-		response["signedUrl"] = rewriteStorageSignedURL(signedURL, api.cfg.SupabasePublicURL)
+		response["signedUrl"] = rewriteStorageSignedURL(signedURL, api.project(r).SupabasePublicURL)
 		delete(response, "signedURL")
 	}
 	writeJSON(w, status, response)
@@ -285,13 +290,13 @@ func (api *API) handleStorageObjectsDownload(w http.ResponseWriter, r *http.Requ
 		Path string `json:"path"`
 	}
 	_ = decodeJSON(r, &payload)
-	target := api.storageBaseURL() + "/object/" + url.PathEscape(bucket) + "/" + strings.TrimPrefix(payload.Path, "/")
+	target := storageBaseURL(api.project(r)) + "/object/" + url.PathEscape(bucket) + "/" + strings.TrimPrefix(payload.Path, "/")
 	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, target, nil)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": err.Error()}})
 		return
 	}
-	req.Header = api.storageHeaders()
+	req.Header = storageHeaders(api.project(r))
 
 	resp, err := api.client.Do(req)
 	if err != nil {
@@ -326,7 +331,7 @@ func (api *API) handleStorageObjectsMove(w http.ResponseWriter, r *http.Request)
 		"sourceKey":      payload.From,
 		"destinationKey": payload.To,
 	})
-	target := api.storageBaseURL() + "/object/move"
+	target := storageBaseURL(api.project(r)) + "/object/move"
 	api.storageProxy(w, r, http.MethodPost, target, bodyBytes)
 }
 
@@ -350,16 +355,18 @@ func (api *API) storageRaw(r *http.Request, method, target string, body []byte)
 	if err != nil {
 		return nil, http.StatusInternalServerError, err
 	}
-	req.Header = api.storageHeaders()
+	req.Header = storageHeaders(api.project(r))
 
 	resp, err := api.client.Do(req)
 	if err != nil {
+		api.logUpstreamFailure(req, 0, nil, err)
 		return nil, http.StatusInternalServerError, err
 	}
 	defer resp.Body.Close()
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode >= 400 {
+		api.logUpstreamFailure(req, resp.StatusCode, respBody, nil)
 		return respBody, resp.StatusCode, nil
 	}
 	return respBody, resp.StatusCode, nil
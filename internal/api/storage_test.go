@@ -47,13 +47,13 @@ func TestStorageBucketCreateSendsIDAndNameLikeOfficialStudio(t *testing.T) {
 	}))
 	defer storage.Close()
 
-	handler := NewRouter(config.Config{
+	handler := newTestRouter(config.NewHolder(config.Config{
 		DefaultProjectName:       "Default Project",
 		DefaultProjectDiskSizeGB: 8,
 		SupabaseURL:              storage.URL,
 		SupabaseServiceKey:       "service-role-key",
 		StateFilePath:            "",
-	})
+	}, ""))
 
 	req := httptest.NewRequest(http.MethodPost, "/platform/storage/default/buckets", strings.NewReader(`{"id":"avatars","public":false}`))
 	req.Header.Set("Content-Type", "application/json")
@@ -108,13 +108,13 @@ func TestStorageBucketUpdateUsesPUTAndSendsIDAndNameLikeOfficialStudio(t *testin
 	}))
 	defer storage.Close()
 
-	handler := NewRouter(config.Config{
+	handler := newTestRouter(config.NewHolder(config.Config{
 		DefaultProjectName:       "Default Project",
 		DefaultProjectDiskSizeGB: 8,
 		SupabaseURL:              storage.URL,
 		SupabaseServiceKey:       "service-role-key",
 		StateFilePath:            "",
-	})
+	}, ""))
 
 	req := httptest.NewRequest(http.MethodPatch, "/platform/storage/default/buckets/avatars", strings.NewReader(`{"public":true,"file_size_limit":1024}`))
 	req.Header.Set("Content-Type", "application/json")
@@ -174,13 +174,13 @@ func TestStorageObjectsListSendsPrefixAndOfficialDefaults(t *testing.T) {
 	}))
 	defer storage.Close()
 
-	handler := NewRouter(config.Config{
+	handler := newTestRouter(config.NewHolder(config.Config{
 		DefaultProjectName:       "Default Project",
 		DefaultProjectDiskSizeGB: 8,
 		SupabaseURL:              storage.URL,
 		SupabaseServiceKey:       "service-role-key",
 		StateFilePath:            "",
-	})
+	}, ""))
 
 	req := httptest.NewRequest(http.MethodPost, "/platform/storage/default/buckets/avatars/objects/list", strings.NewReader(`{"path":"","options":{}}`))
 	req.Header.Set("Content-Type", "application/json")
@@ -238,13 +238,13 @@ func TestStorageObjectsDeleteUsesOfficialRouteAndPayload(t *testing.T) {
 	}))
 	defer storage.Close()
 
-	handler := NewRouter(config.Config{
+	handler := newTestRouter(config.NewHolder(config.Config{
 		DefaultProjectName:       "Default Project",
 		DefaultProjectDiskSizeGB: 8,
 		SupabaseURL:              storage.URL,
 		SupabaseServiceKey:       "service-role-key",
 		StateFilePath:            "",
-	})
+	}, ""))
 
 	req := httptest.NewRequest(
 		http.MethodDelete,
@@ -300,14 +300,14 @@ func TestStorageObjectsSignUsesSingleObjectRouteAndNormalizesSignedURL(t *testin
 	}))
 	defer storage.Close()
 
-	handler := NewRouter(config.Config{
+	handler := newTestRouter(config.NewHolder(config.Config{
 		DefaultProjectName:       "Default Project",
 		DefaultProjectDiskSizeGB: 8,
 		SupabaseURL:              storage.URL,
 		SupabasePublicURL:        "https://database-eu.fichuo.de",
 		SupabaseServiceKey:       "service-role-key",
 		StateFilePath:            "",
-	})
+	}, ""))
 
 	req := httptest.NewRequest(
 		http.MethodPost,
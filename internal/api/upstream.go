@@ -0,0 +1,74 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/logging"
+)
+
+const maxLoggedUpstreamBody = 512
+
+// upstreamName labels outgoing requests for metrics and traces by matching them against the configured services.
+func (api *API) upstreamName(req *http.Request) string {
+	cfg := api.config()
+	target := req.URL.String()
+	for _, project := range cfg.AllProjects() {
+		switch {
+		case hasURLPrefix(target, project.StudioPgMetaURL):
+			return "pg-meta"
+		case hasURLPrefix(target, project.LogflareURL):
+			return "logflare"
+		case hasURLPrefix(target, authBaseURL(project)):
+			return "gotrue"
+		case hasURLPrefix(target, storageBaseURL(project)):
+			return "storage"
+		case hasURLPrefix(target, project.SupabaseURL):
+			return "supabase"
+		}
+	}
+	if hasURLPrefix(target, cfg.SupportAPIURL) {
+		return "support"
+	}
+
+	if openAI, err := url.Parse(resolveOpenAIChatCompletionsURL(cfg.OpenAIAPIURL)); err == nil && req.URL.Host == openAI.Host {
+		return "openai"
+	}
+	return "other"
+}
+
+func hasURLPrefix(target, base string) bool {
+	base = strings.TrimSuffix(strings.TrimSpace(base), "/")
+	if base == "" || !strings.HasPrefix(target, base) {
+		return false
+	}
+	rest := target[len(base):]
+	return rest == "" || rest[0] == '/' || rest[0] == '?'
+}
+
+// logUpstreamFailure records an upstream call that errored or answered with a 5xx/4xx status.
+// Secret headers and credential query parameters are redacted.
+func (api *API) logUpstreamFailure(req *http.Request, status int, body []byte, err error) {
+	attrs := []slog.Attr{
+		slog.String("upstream", api.upstreamName(req)),
+		slog.String("method", req.Method),
+		slog.String("url", logging.RedactURL(req.URL)),
+		slog.Any("headers", logging.RedactHeaders(req.Header)),
+	}
+	level := slog.LevelWarn
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", err.Error()))
+	} else {
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		if len(body) > maxLoggedUpstreamBody {
+			body = body[:maxLoggedUpstreamBody]
+		}
+		attrs = append(attrs, slog.Int("status", status), slog.String("body", string(body)))
+	}
+	slog.LogAttrs(req.Context(), level, "upstream request failed", attrs...)
+}
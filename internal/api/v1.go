@@ -14,7 +14,7 @@ func (api *API) handleV1ApiKeys(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, []any{
 		map[string]any{
 			"name":        "anon",
-			"api_key":     api.cfg.SupabaseAnonKey,
+			"api_key":     api.project(r).SupabaseAnonKey,
 			"id":          "anon",
 			"type":        "legacy",
 			"hash":        "",
@@ -23,7 +23,7 @@ func (api *API) handleV1ApiKeys(w http.ResponseWriter, r *http.Request) {
 		},
 		map[string]any{
 			"name":        "service_role",
-			"api_key":     api.cfg.SupabaseServiceKey,
+			"api_key":     projectSecret(r, api.project(r).SupabaseServiceKey),
 			"id":          "service_role",
 			"type":        "legacy",
 			"hash":        "",
@@ -38,7 +38,7 @@ func (api *API) handleFunctions(w http.ResponseWriter, r *http.Request) {
 		writeMethodNotAllowed(w, r, "GET")
 		return
 	}
-	functions, err := api.listFunctions()
+	functions, err := api.listFunctions(api.project(r))
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": err.Error()}})
 		return
@@ -56,7 +56,7 @@ func (api *API) handleFunctionBySlug(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]any{"message": "Missing function 'slug' parameter"}})
 		return
 	}
-	function, err := api.getFunctionBySlug(slug)
+	function, err := api.getFunctionBySlug(api.project(r), slug)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]any{"message": "Function not found"}})
 		return
@@ -71,7 +71,7 @@ func (api *API) handleTypescriptTypes(w http.ResponseWriter, r *http.Request) {
 	}
 	included := "public,graphql_public,storage"
 	excluded := "auth,cron,extensions,graphql,net,pgsodium,pgsodium_masks,realtime,supabase_functions,supabase_migrations,vault,_analytics,_realtime"
-	target := api.cfg.StudioPgMetaURL + "/generators/typescript?included_schema=" + included + "&excluded_schemas=" + excluded
+	target := api.project(r).StudioPgMetaURL + "/generators/typescript?included_schema=" + included + "&excluded_schemas=" + excluded
 
 	headers, err := api.pgMetaHeaders(r, false)
 	if err != nil {
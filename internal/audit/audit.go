@@ -0,0 +1,395 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	currentFileName = "audit.jsonl"
+	rotatedPrefix   = "audit-"
+	rotatedLayout   = "20060102T150405.000000000"
+)
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Time       time.Time         `json:"time"`
+	RequestID  string            `json:"request_id,omitempty"`
+	ClientIP   string            `json:"client_ip,omitempty"`
+	User       string            `json:"user,omitempty"`
+	Role       string            `json:"role,omitempty"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Route      string            `json:"route,omitempty"`
+	Target     string            `json:"target,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+	Status     int               `json:"status"`
+	Outcome    string            `json:"outcome"`
+	DurationMS int64             `json:"duration_ms"`
+}
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+func OutcomeForStatus(status int) string {
+	switch {
+	case status == 401 || status == 403:
+		return OutcomeDenied
+	case status >= 400:
+		return OutcomeFailure
+	default:
+		return OutcomeSuccess
+	}
+}
+
+type Options struct {
+	Dir string
+	// MaxSize and MaxAge trigger a rotation of the current file. Zero disables the check.
+	MaxSize int64
+	MaxAge  time.Duration
+	// MaxFiles is the number of rotated files kept; older ones are deleted on rotation.
+	// Zero keeps them all.
+	MaxFiles int
+}
+
+// Logger appends entries to a JSONL file in Dir and rotates it by size and age, keeping the
+// newest MaxFiles rotated files.
+type Logger struct {
+	opts Options
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func New(opts Options) (*Logger, error) {
+	if strings.TrimSpace(opts.Dir) == "" {
+		return nil, errors.New("audit log directory is required")
+	}
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	logger := &Logger{opts: opts}
+	if err := logger.open(); err != nil {
+		return nil, err
+	}
+	if err := logger.prune(); err != nil {
+		logger.file.Close()
+		return nil, err
+	}
+	return logger, nil
+}
+
+func (l *Logger) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.shouldRotate(int64(len(line)), entry.Time) {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+func (l *Logger) shouldRotate(next int64, now time.Time) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.opts.MaxSize > 0 && l.size+next > l.opts.MaxSize {
+		return true
+	}
+	return l.opts.MaxAge > 0 && now.Sub(l.openedAt) >= l.opts.MaxAge
+}
+
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	current := filepath.Join(l.opts.Dir, currentFileName)
+	rotated := filepath.Join(l.opts.Dir, rotatedPrefix+time.Now().UTC().Format(rotatedLayout)+".jsonl")
+	if err := os.Rename(current, rotated); err != nil {
+		return err
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+	return l.prune()
+}
+
+// prune deletes the oldest rotated files beyond MaxFiles.
+func (l *Logger) prune() error {
+	if l.opts.MaxFiles <= 0 {
+		return nil
+	}
+	files, err := l.files()
+	if err != nil {
+		return err
+	}
+	rotated := files[:len(files)-1]
+	for _, path := range rotated[:max(0, len(rotated)-l.opts.MaxFiles)] {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Logger) open() error {
+	path := filepath.Join(l.opts.Dir, currentFileName)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file = file
+	l.size = info.Size()
+	l.openedAt = time.Now()
+	if l.size > 0 {
+		if first, ok := firstEntryTime(path); ok {
+			l.openedAt = first
+		}
+	}
+	return nil
+}
+
+// Filter narrows a Query. Empty fields match everything.
+type Filter struct {
+	User    string
+	Method  string
+	Outcome string
+	Target  string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+	Offset  int
+}
+
+// Page is one page of a Query. HasMore reports whether older matches follow it.
+type Page struct {
+	Entries []Entry `json:"entries"`
+	HasMore bool    `json:"has_more"`
+	Limit   int     `json:"limit"`
+	Offset  int     `json:"offset"`
+}
+
+// Query returns matching entries newest first. It reads the current file, then rotated files
+// from the newest back, and stops once the page is full.
+func (l *Logger) Query(filter Filter) (Page, error) {
+	if filter.Limit <= 0 || filter.Limit > 1000 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	page := Page{Entries: []Entry{}, Limit: filter.Limit, Offset: filter.Offset}
+	if l == nil {
+		return page, nil
+	}
+
+	l.mu.Lock()
+	files, err := l.files()
+	l.mu.Unlock()
+	if err != nil {
+		return page, err
+	}
+
+	skip := filter.Offset
+	for i := len(files) - 1; i >= 0; i-- {
+		var matches []Entry
+		if err := scanFile(files[i], func(entry Entry) {
+			if filter.matches(entry) {
+				matches = append(matches, entry)
+			}
+		}); err != nil {
+			return page, err
+		}
+		for j := len(matches) - 1; j >= 0; j-- {
+			switch {
+			case skip > 0:
+				skip--
+			case len(page.Entries) < filter.Limit:
+				page.Entries = append(page.Entries, matches[j])
+			default:
+				page.HasMore = true
+				return page, nil
+			}
+		}
+	}
+	return page, nil
+}
+
+// files lists log files oldest first.
+func (l *Logger) files() ([]string, error) {
+	dirEntries, err := os.ReadDir(l.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var rotated []string
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, rotatedPrefix) && strings.HasSuffix(name, ".jsonl") {
+			rotated = append(rotated, filepath.Join(l.opts.Dir, name))
+		}
+	}
+	sort.Strings(rotated)
+	return append(rotated, filepath.Join(l.opts.Dir, currentFileName)), nil
+}
+
+func (f Filter) matches(entry Entry) bool {
+	if f.User != "" && !strings.EqualFold(entry.User, f.User) {
+		return false
+	}
+	if f.Method != "" && !strings.EqualFold(entry.Method, f.Method) {
+		return false
+	}
+	if f.Outcome != "" && entry.Outcome != f.Outcome {
+		return false
+	}
+	if f.Target != "" && !strings.Contains(entry.Target, f.Target) && !strings.Contains(entry.Path, f.Target) {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func scanFile(path string, fn func(Entry)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, err := readLine(reader)
+		var entry Entry
+		if len(line) > 0 && json.Unmarshal(line, &entry) == nil {
+			fn(entry)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// maxLineBytes bounds the entries read back. Longer lines, which Record does not write since
+// details are truncated, are skipped rather than failing every query of their file.
+const maxLineBytes = 1024 * 1024
+
+// readLine returns the next line of reader, or nil for a line over maxLineBytes.
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong && len(line)+len(chunk) > maxLineBytes {
+			tooLong, line = true, nil
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, err
+		}
+	}
+}
+
+func firstEntryTime(path string) (time.Time, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer file.Close()
+
+	line, err := readLine(bufio.NewReader(file))
+	if err != nil && len(line) == 0 {
+		return time.Time{}, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(line, &entry); err != nil || entry.Time.IsZero() {
+		return time.Time{}, false
+	}
+	return entry.Time, true
+}
+
+type detailsContextKey struct{}
+
+// WithDetails attaches a details map that handlers can fill in through Annotate.
+func WithDetails(ctx context.Context, details map[string]string) context.Context {
+	return context.WithValue(ctx, detailsContextKey{}, details)
+}
+
+// maxDetailBytes bounds each detail value; longer ones are cut off.
+const maxDetailBytes = 4096
+
+// Annotate adds a detail, such as the SQL text, to the audit entry of the current request.
+// SQL should go through RedactSQL first.
+func Annotate(ctx context.Context, key, value string) {
+	if details, ok := ctx.Value(detailsContextKey{}).(map[string]string); ok {
+		details[key] = truncate(value, maxDetailBytes)
+	}
+}
+
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut] + "…"
+}
@@ -0,0 +1,164 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerRotatesBySizeAndKeepsRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := New(Options{Dir: dir, MaxSize: 400})
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer logger.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := logger.Record(Entry{Method: "POST", Path: "/platform/pg-meta/default/query", Status: 200, Outcome: OutcomeSuccess}); err != nil {
+			t.Fatalf("failed to record entry: %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("expected rotated files, got %v (%v)", files, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, currentFileName))
+	if err != nil || info.Size() > 400 {
+		t.Fatalf("expected current file to stay under the size limit, got %v (%v)", info, err)
+	}
+
+	page, err := logger.Query(Filter{Limit: 100})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if len(page.Entries) != 10 || page.HasMore {
+		t.Fatalf("expected all 10 entries across rotated files, got %d", len(page.Entries))
+	}
+}
+
+func TestLoggerPrunesOldRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := New(Options{Dir: dir, MaxSize: 200, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer logger.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := logger.Record(Entry{Method: "POST", Path: "/platform/pg-meta/default/query", Status: 200, Outcome: OutcomeSuccess}); err != nil {
+			t.Fatalf("failed to record entry: %v", err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if len(files) != 2 {
+		t.Fatalf("expected two rotated files to be kept, got %v", files)
+	}
+}
+
+func TestLoggerRotatesByAge(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := New(Options{Dir: dir, MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer logger.Close()
+
+	now := time.Now().UTC()
+	if err := logger.Record(Entry{Time: now, Method: "POST", Path: "/a", Status: 200}); err != nil {
+		t.Fatalf("failed to record entry: %v", err)
+	}
+	if err := logger.Record(Entry{Time: now.Add(2 * time.Hour), Method: "POST", Path: "/b", Status: 200}); err != nil {
+		t.Fatalf("failed to record entry: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if len(files) != 1 {
+		t.Fatalf("expected one rotated file, got %v", files)
+	}
+}
+
+func TestLoggerQueryFiltersAndPagesNewestFirst(t *testing.T) {
+	logger, err := New(Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer logger.Close()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		user := "alice"
+		if i%2 == 1 {
+			user = "bob"
+		}
+		status := 200
+		if i == 4 {
+			status = 403
+		}
+		_ = logger.Record(Entry{
+			Time:    start.Add(time.Duration(i) * time.Minute),
+			User:    user,
+			Method:  "DELETE",
+			Path:    "/platform/storage/default/buckets/b" + string(rune('0'+i)),
+			Status:  status,
+			Outcome: OutcomeForStatus(status),
+		})
+	}
+
+	page, err := logger.Query(Filter{User: "alice", Limit: 2})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if len(page.Entries) != 2 || !page.HasMore {
+		t.Fatalf("expected 2 matches on the page with more to follow, got %d, %v", len(page.Entries), page.HasMore)
+	}
+	if !strings.HasSuffix(page.Entries[0].Path, "b4") || page.Entries[0].Outcome != OutcomeDenied {
+		t.Fatalf("expected newest entry first, got %#v", page.Entries[0])
+	}
+
+	page, _ = logger.Query(Filter{User: "alice", Limit: 2, Offset: 2})
+	if len(page.Entries) != 1 || page.HasMore || !strings.HasSuffix(page.Entries[0].Path, "b0") {
+		t.Fatalf("expected last page to hold the oldest entry, got %#v", page.Entries)
+	}
+
+	page, _ = logger.Query(Filter{Since: start.Add(90 * time.Second), Until: start.Add(3 * time.Minute)})
+	if len(page.Entries) != 2 {
+		t.Fatalf("expected 2 entries in time range, got %d", len(page.Entries))
+	}
+}
+
+func TestLoggerQuerySkipsOversizedLines(t *testing.T) {
+	dir := t.TempDir()
+	huge := `{"method":"POST","path":"/` + strings.Repeat("x", 2*maxLineBytes) + `"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, currentFileName), []byte(huge), 0o600); err != nil {
+		t.Fatalf("failed to write audit file: %v", err)
+	}
+	logger, err := New(Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer logger.Close()
+	if err := logger.Record(Entry{Method: "DELETE", Path: "/after", Status: 200}); err != nil {
+		t.Fatalf("failed to record entry: %v", err)
+	}
+
+	page, err := logger.Query(Filter{})
+	if err != nil {
+		t.Fatalf("expected the oversized line to be skipped, got %v", err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].Path != "/after" {
+		t.Fatalf("expected only the regular entry, got %d entries", len(page.Entries))
+	}
+}
+
+func TestAnnotateTruncatesLongDetails(t *testing.T) {
+	details := map[string]string{}
+	Annotate(WithDetails(t.Context(), details), "query", strings.Repeat("é", maxDetailBytes))
+	if got := details["query"]; len(got) > maxDetailBytes+len("…") || !strings.HasSuffix(got, "é…") {
+		t.Fatalf("expected the detail to be cut at a rune boundary, got %d bytes", len(got))
+	}
+}
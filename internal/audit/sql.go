@@ -0,0 +1,88 @@
+package audit
+
+import "strings"
+
+// RedactSQL replaces the string literals of query, quoted or dollar-quoted, with '?', so the
+// audit log keeps the shape of a statement without passwords or row data in its values.
+func RedactSQL(query string) string {
+	var out strings.Builder
+	out.Grow(len(query))
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == '\'':
+			escapes := i > 0 && (query[i-1] == 'E' || query[i-1] == 'e')
+			end := i + 1
+			for end < len(query) {
+				if query[end] == '\'' {
+					if end+1 < len(query) && query[end+1] == '\'' {
+						end += 2
+						continue
+					}
+					break
+				}
+				if escapes && query[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			out.WriteString("'?'")
+			i = end + 1
+		case c == '$':
+			tag, ok := dollarTag(query[i:])
+			if !ok {
+				out.WriteByte(c)
+				i++
+				continue
+			}
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				out.WriteString("'?'")
+				i = len(query)
+				continue
+			}
+			out.WriteString("'?'")
+			i += len(tag) + end + len(tag)
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 4
+			}
+			out.WriteString(query[i : i+end+4])
+			i += end + 4
+		case c == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				end = len(query) - i - 1
+			}
+			out.WriteString(query[i : i+end+2])
+			i += end + 2
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string at the start of s, such as $$
+// or $body$. Positional parameters such as $1 are not tags.
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c == '$' {
+			return s[:i+1], true
+		}
+		isLetter := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+		if !isLetter && !(i > 1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,19 @@
+package audit
+
+import "testing"
+
+func TestRedactSQLHidesLiterals(t *testing.T) {
+	cases := map[string]string{
+		`select * from users where email = 'a@b.c' and id = $1`:        `select * from users where email = '?' and id = $1`,
+		`alter role app password 'it''s secret';`:                      `alter role app password '?';`,
+		`select E'\'escaped\'' as "it's", 'x'`:                         `select E'?' as "it's", '?'`,
+		"create function f() returns int as $body$ select 'x' $body$;": "create function f() returns int as '?';",
+		"-- don't redact comments\nselect 'v' /* it's */":              "-- don't redact comments\nselect '?' /* it's */",
+		`insert into t values ('unterminated`:                          `insert into t values ('?'`,
+	}
+	for query, want := range cases {
+		if got := RedactSQL(query); got != want {
+			t.Errorf("RedactSQL(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
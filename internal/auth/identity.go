@@ -0,0 +1,30 @@
+package auth
+
+import "context"
+
+// Identity describes the dashboard user attached to an authenticated request.
+type Identity struct {
+	Username string   `json:"username"`
+	Email    string   `json:"email,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	Role     Role     `json:"role,omitempty"`
+	// Provider is "local" for users file accounts and "oidc" for single sign-on.
+	Provider string `json:"provider,omitempty"`
+}
+
+const (
+	ProviderLocal = "local"
+	ProviderOIDC  = "oidc"
+)
+
+type identityContextKey struct{}
+
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityContextKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var ErrAccessDenied = errors.New("user is not a member of an allowed group")
+
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	Scopes        []string
+	GroupsClaim   string
+	AllowedGroups []string
+	// AdminGroups and DeveloperGroups grant those roles; everyone else gets DefaultRole.
+	AdminGroups     []string
+	DeveloperGroups []string
+	DefaultRole     Role
+	// HTTPClient is used for discovery, key fetches and the token exchange. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// OIDCProvider signs dashboard users in through an OpenID Connect IdP using the
+// authorization code flow with PKCE. Discovery happens lazily so that an IdP outage
+// at startup does not keep the server from booting.
+type OIDCProvider struct {
+	cfg OIDCConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCProvider(cfg OIDCConfig) *OIDCProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if role, ok := ParseRole(string(cfg.DefaultRole)); ok {
+		cfg.DefaultRole = role
+	} else {
+		cfg.DefaultRole = RoleViewer
+	}
+	return &OIDCProvider{cfg: cfg}
+}
+
+// NewLoginState generates fresh state, nonce and PKCE verifier values for a sign-in attempt.
+func NewLoginState(next string) LoginState {
+	return LoginState{
+		State:    randomToken(),
+		Nonce:    randomToken(),
+		Verifier: oauth2.GenerateVerifier(),
+		Next:     next,
+	}
+}
+
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, redirectURL string, state LoginState) (string, error) {
+	provider, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(provider, redirectURL).AuthCodeURL(
+		state.State,
+		oidc.Nonce(state.Nonce),
+		oauth2.S256ChallengeOption(state.Verifier),
+	), nil
+}
+
+// Exchange redeems an authorization code and turns the verified ID token into an Identity.
+func (p *OIDCProvider) Exchange(ctx context.Context, redirectURL, code string, state LoginState) (Identity, error) {
+	provider, verifier, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	ctx = oidc.ClientContext(ctx, p.cfg.HTTPClient)
+	token, err := p.oauth2Config(provider, redirectURL).Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("token exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return Identity{}, errors.New("token response did not include an id_token")
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if idToken.Nonce != state.Nonce {
+		return Identity{}, errors.New("id_token nonce mismatch")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, err
+	}
+	identity := Identity{
+		Username: firstClaim(claims, "preferred_username", "email", "sub"),
+		Email:    firstClaim(claims, "email"),
+		Name:     firstClaim(claims, "name"),
+		Groups:   stringsClaim(claims[p.cfg.GroupsClaim]),
+		Provider: ProviderOIDC,
+	}
+	if identity.Username == "" {
+		identity.Username = idToken.Subject
+	}
+	if len(p.cfg.AllowedGroups) > 0 && !memberOf(identity.Groups, p.cfg.AllowedGroups) {
+		return identity, ErrAccessDenied
+	}
+	identity.Role = p.roleFor(identity.Groups)
+	return identity, nil
+}
+
+func (p *OIDCProvider) roleFor(groups []string) Role {
+	switch {
+	case memberOf(groups, p.cfg.AdminGroups):
+		return RoleAdmin
+	case memberOf(groups, p.cfg.DeveloperGroups):
+		return RoleDeveloper
+	default:
+		return p.cfg.DefaultRole
+	}
+}
+
+func memberOf(groups, candidates []string) bool {
+	for _, candidate := range candidates {
+		for _, group := range groups {
+			if group == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider != nil {
+		return p.provider, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, p.cfg.HTTPClient), p.cfg.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	p.provider = provider
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.provider, p.verifier, nil
+}
+
+func (p *OIDCProvider) oauth2Config(provider *oidc.Provider, redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       p.cfg.Scopes,
+	}
+}
+
+func firstClaim(claims map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := claims[key].(string); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// stringsClaim accepts both list and space/comma separated string forms of a groups claim.
+func stringsClaim(value any) []string {
+	var values []string
+	switch typed := value.(type) {
+	case []any:
+		for _, item := range typed {
+			if text, ok := item.(string); ok && text != "" {
+				values = append(values, text)
+			}
+		}
+	case string:
+		values = strings.FieldsFunc(typed, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	return values
+}
+
+func randomToken() string {
+	bytes := make([]byte, 24)
+	_, _ = rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIdP is a minimal OpenID provider: discovery, JWKS and a token endpoint that
+// enforces PKCE. The authorization step is simulated by calling authorize directly.
+type testIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]any
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+}
+
+func newTestIdP(t *testing.T, claims map[string]any) *testIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	idp := &testIdP{key: key, claims: claims}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []any{map[string]any{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != "test-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		idp.mu.Lock()
+		challenge, nonce := idp.challenge, idp.nonce
+		idp.mu.Unlock()
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":   idp.server.URL,
+			"aud":   "studio",
+			"sub":   "user-1",
+			"nonce": nonce,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+		for key, value := range idp.claims {
+			claims[key] = value
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) authorize(t *testing.T, authURL string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid auth URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("expected a PKCE S256 challenge, got %q", authURL)
+	}
+	idp.mu.Lock()
+	idp.challenge = query.Get("code_challenge")
+	idp.nonce = query.Get("nonce")
+	idp.mu.Unlock()
+}
+
+func (idp *testIdP) provider(allowedGroups ...string) *OIDCProvider {
+	return NewOIDCProvider(OIDCConfig{
+		Issuer:          idp.server.URL,
+		ClientID:        "studio",
+		ClientSecret:    "secret",
+		AllowedGroups:   allowedGroups,
+		DeveloperGroups: []string{"engineering"},
+		AdminGroups:     []string{"platform-admins"},
+		HTTPClient:      idp.server.Client(),
+	})
+}
+
+func TestOIDCProviderSignsInWithAuthorizationCodeAndPKCE(t *testing.T) {
+	idp := newTestIdP(t, map[string]any{
+		"email":              "alice@example.com",
+		"preferred_username": "alice",
+		"name":               "Alice Example",
+		"groups":             []string{"engineering", "studio-users"},
+	})
+	provider := idp.provider("studio-users")
+	ctx := context.Background()
+
+	state := NewLoginState("/project/default")
+	authURL, err := provider.AuthCodeURL(ctx, "http://studio.test/auth/oidc/callback", state)
+	if err != nil {
+		t.Fatalf("failed to build auth URL: %v", err)
+	}
+	idp.authorize(t, authURL)
+
+	identity, err := provider.Exchange(ctx, "http://studio.test/auth/oidc/callback", "test-code", state)
+	if err != nil {
+		t.Fatalf("expected exchange to succeed, got %v", err)
+	}
+	if identity.Username != "alice" || identity.Email != "alice@example.com" || identity.Name != "Alice Example" {
+		t.Fatalf("unexpected identity %#v", identity)
+	}
+	if len(identity.Groups) != 2 || identity.Provider != ProviderOIDC {
+		t.Fatalf("expected groups and provider to be populated, got %#v", identity)
+	}
+	if identity.Role != RoleDeveloper {
+		t.Fatalf("expected engineering group to map to developer, got %q", identity.Role)
+	}
+}
+
+func TestOIDCProviderRejectsUsersOutsideAllowedGroups(t *testing.T) {
+	idp := newTestIdP(t, map[string]any{"email": "bob@example.com", "groups": []string{"sales"}})
+	provider := idp.provider("studio-users")
+	ctx := context.Background()
+
+	state := NewLoginState("")
+	authURL, err := provider.AuthCodeURL(ctx, "http://studio.test/auth/oidc/callback", state)
+	if err != nil {
+		t.Fatalf("failed to build auth URL: %v", err)
+	}
+	idp.authorize(t, authURL)
+
+	if _, err := provider.Exchange(ctx, "http://studio.test/auth/oidc/callback", "test-code", state); !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("expected access denied, got %v", err)
+	}
+}
+
+func TestOIDCProviderRejectsMismatchedVerifierAndNonce(t *testing.T) {
+	idp := newTestIdP(t, map[string]any{"email": "alice@example.com"})
+	provider := idp.provider()
+	ctx := context.Background()
+
+	state := NewLoginState("")
+	authURL, err := provider.AuthCodeURL(ctx, "http://studio.test/auth/oidc/callback", state)
+	if err != nil {
+		t.Fatalf("failed to build auth URL: %v", err)
+	}
+	idp.authorize(t, authURL)
+
+	wrongVerifier := state
+	wrongVerifier.Verifier = NewLoginState("").Verifier
+	if _, err := provider.Exchange(ctx, "http://studio.test/auth/oidc/callback", "test-code", wrongVerifier); err == nil {
+		t.Fatalf("expected exchange with the wrong PKCE verifier to fail")
+	}
+
+	wrongNonce := state
+	wrongNonce.Nonce = "replayed"
+	if _, err := provider.Exchange(ctx, "http://studio.test/auth/oidc/callback", "test-code", wrongNonce); err == nil {
+		t.Fatalf("expected exchange with a mismatched nonce to fail")
+	}
+}
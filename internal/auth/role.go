@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// Role decides what a dashboard user may do. Viewers only read and query the database
+// through the read-only Postgres user, developers may change data, admins may also
+// change project level settings.
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"
+	RoleDeveloper Role = "developer"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer:    1,
+	RoleDeveloper: 2,
+	RoleAdmin:     3,
+}
+
+func ParseRole(value string) (Role, bool) {
+	role := Role(strings.ToLower(strings.TrimSpace(value)))
+	_, ok := roleRank[role]
+	return role, ok
+}
+
+// Allows reports whether r is at least as privileged as required.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+type loginDisabledContextKey struct{}
+
+// WithoutLogin marks ctx as served with dashboard authentication disabled, where every
+// request acts as an admin.
+func WithoutLogin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loginDisabledContextKey{}, true)
+}
+
+// RoleFromContext returns the role of the signed-in user. A request without an identity is
+// an admin only when marked by WithoutLogin; otherwise it has no role and every route
+// policy rejects it.
+func RoleFromContext(ctx context.Context) Role {
+	identity, ok := IdentityFromContext(ctx)
+	if !ok {
+		if disabled, _ := ctx.Value(loginDisabledContextKey{}).(bool); disabled {
+			return RoleAdmin
+		}
+		return ""
+	}
+	if _, known := roleRank[identity.Role]; !known {
+		return RoleViewer
+	}
+	return identity.Role
+}
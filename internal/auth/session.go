@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	SessionCookieName = "supabase-studio-go-session"
+
+	loginStateCookieName = "supabase-studio-go-login"
+	loginStateTTL        = 10 * time.Minute
+)
+
+var ErrNoSession = errors.New("no valid session")
+
+// IsSessionCookie reports whether name is one of the dashboard's own cookies.
+func IsSessionCookie(name string) bool {
+	return name == SessionCookieName || name == loginStateCookieName
+}
+
+type sessionClaims struct {
+	Email    string   `json:"email,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	Role     Role     `json:"role,omitempty"`
+	Provider string   `json:"provider,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Revocations keeps logged-out sessions where every replica sees them, until they would
+// have expired anyway.
+type Revocations interface {
+	Revoke(ctx context.Context, id string, expiresAt time.Time) error
+	Revoked(id string) bool
+}
+
+// SessionManager issues HMAC-signed session cookies and tracks logged-out sessions
+// until they would have expired anyway.
+type SessionManager struct {
+	secret     []byte
+	ttl        time.Duration
+	cookiePath string
+	shared     Revocations
+
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+// NewSessionManager signs sessions with secret. An empty secret falls back to a random
+// per-process key, which means sessions do not survive a restart. Logouts are recorded in
+// shared as well as in this process; with a nil shared, a logged-out session is accepted
+// again after a restart and by other replicas.
+func NewSessionManager(secret string, ttl time.Duration, cookiePath string, shared Revocations) *SessionManager {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	if ttl <= 0 {
+		ttl = 12 * time.Hour
+	}
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+	return &SessionManager{
+		secret:     key,
+		ttl:        ttl,
+		cookiePath: cookiePath,
+		shared:     shared,
+		revoked:    map[string]time.Time{},
+	}
+}
+
+func (m *SessionManager) Issue(w http.ResponseWriter, r *http.Request, identity Identity) error {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+	claims := sessionClaims{
+		Email:    identity.Email,
+		Name:     identity.Name,
+		Groups:   identity.Groups,
+		Role:     identity.Role,
+		Provider: identity.Provider,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   identity.Username,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     m.cookiePath,
+		Expires:  expiresAt,
+		MaxAge:   int(m.ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// LoginState carries the single sign-on request parameters across the redirect to the IdP.
+type LoginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Next     string `json:"next,omitempty"`
+}
+
+type loginStateClaims struct {
+	LoginState
+	jwt.RegisteredClaims
+}
+
+func (m *SessionManager) IssueLoginState(w http.ResponseWriter, r *http.Request, state LoginState) error {
+	expiresAt := time.Now().Add(loginStateTTL)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, loginStateClaims{
+		LoginState:       state,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)},
+	}).SignedString(m.secret)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginStateCookieName,
+		Value:    token,
+		Path:     m.cookiePath,
+		Expires:  expiresAt,
+		MaxAge:   int(loginStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// TakeLoginState returns the pending login state and clears its cookie, so a callback can only be used once.
+func (m *SessionManager) TakeLoginState(w http.ResponseWriter, r *http.Request) (LoginState, error) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginStateCookieName,
+		Value:    "",
+		Path:     m.cookiePath,
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	cookie, err := r.Cookie(loginStateCookieName)
+	if err != nil || cookie.Value == "" {
+		return LoginState{}, ErrNoSession
+	}
+	var claims loginStateClaims
+	parsed, err := jwt.ParseWithClaims(cookie.Value, &claims, func(token *jwt.Token) (any, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !parsed.Valid {
+		return LoginState{}, ErrNoSession
+	}
+	return claims.LoginState, nil
+}
+
+func (m *SessionManager) Identity(r *http.Request) (Identity, error) {
+	claims, err := m.parse(r)
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{
+		Username: claims.Subject,
+		Email:    claims.Email,
+		Name:     claims.Name,
+		Groups:   claims.Groups,
+		Role:     claims.Role,
+		Provider: claims.Provider,
+	}, nil
+}
+
+// Revoke invalidates the session carried by r and clears the cookie.
+func (m *SessionManager) Revoke(w http.ResponseWriter, r *http.Request) {
+	if claims, err := m.parse(r); err == nil && claims.ExpiresAt != nil {
+		m.mu.Lock()
+		now := time.Now()
+		for id, expiresAt := range m.revoked {
+			if expiresAt.Before(now) {
+				delete(m.revoked, id)
+			}
+		}
+		m.revoked[claims.ID] = claims.ExpiresAt.Time
+		m.mu.Unlock()
+
+		if m.shared != nil {
+			if err := m.shared.Revoke(r.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+				slog.Error("failed to share a session revocation, other replicas still accept it", "user", claims.Subject, "error", err)
+			}
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     m.cookiePath,
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (m *SessionManager) parse(r *http.Request) (*sessionClaims, error) {
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, ErrNoSession
+	}
+
+	var claims sessionClaims
+	parsed, err := jwt.ParseWithClaims(cookie.Value, &claims, func(token *jwt.Token) (any, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !parsed.Valid || claims.Subject == "" {
+		return nil, ErrNoSession
+	}
+
+	m.mu.Lock()
+	_, revoked := m.revoked[claims.ID]
+	m.mu.Unlock()
+	if revoked || (m.shared != nil && m.shared.Revoked(claims.ID)) {
+		return nil, ErrNoSession
+	}
+
+	return &claims, nil
+}
+
+func isSecureRequest(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSessionManagerRoundTripsIdentity(t *testing.T) {
+	sessions := NewSessionManager("test-secret", time.Hour, "/", nil)
+
+	rec := httptest.NewRecorder()
+	if err := sessions.Issue(rec, httptest.NewRequest(http.MethodPost, "/auth/sign-in", nil), Identity{Username: "alice", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("failed to issue session: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+
+	identity, err := sessions.Identity(req)
+	if err != nil {
+		t.Fatalf("expected session to be valid, got %v", err)
+	}
+	if identity.Username != "alice" || identity.Email != "alice@example.com" {
+		t.Fatalf("unexpected identity %#v", identity)
+	}
+
+	other := NewSessionManager("other-secret", time.Hour, "/", nil)
+	if _, err := other.Identity(req); err == nil {
+		t.Fatalf("expected session signed with a different secret to be rejected")
+	}
+}
+
+func TestSessionManagerRevokeInvalidatesSession(t *testing.T) {
+	sessions := NewSessionManager("test-secret", time.Hour, "/", nil)
+
+	rec := httptest.NewRecorder()
+	if err := sessions.Issue(rec, httptest.NewRequest(http.MethodPost, "/auth/sign-in", nil), Identity{Username: "alice"}); err != nil {
+		t.Fatalf("failed to issue session: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+
+	logoutRec := httptest.NewRecorder()
+	sessions.Revoke(logoutRec, req)
+
+	if _, err := sessions.Identity(req); err == nil {
+		t.Fatalf("expected revoked session to be rejected")
+	}
+
+	cleared := logoutRec.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Fatalf("expected logout to clear the session cookie, got %#v", cleared)
+	}
+}
+
+type sharedRevocations map[string]time.Time
+
+func (s sharedRevocations) Revoke(_ context.Context, id string, expiresAt time.Time) error {
+	s[id] = expiresAt
+	return nil
+}
+
+func (s sharedRevocations) Revoked(id string) bool {
+	_, revoked := s[id]
+	return revoked
+}
+
+func TestSessionManagerSharesRevocationsWithOtherReplicas(t *testing.T) {
+	shared := sharedRevocations{}
+	first := NewSessionManager("test-secret", time.Hour, "/", shared)
+	second := NewSessionManager("test-secret", time.Hour, "/", shared)
+
+	rec := httptest.NewRecorder()
+	if err := first.Issue(rec, httptest.NewRequest(http.MethodPost, "/auth/sign-in", nil), Identity{Username: "alice"}); err != nil {
+		t.Fatalf("failed to issue session: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	if _, err := second.Identity(req); err != nil {
+		t.Fatalf("expected the other replica to accept the session, got %v", err)
+	}
+
+	first.Revoke(httptest.NewRecorder(), req)
+
+	if _, err := second.Identity(req); err == nil {
+		t.Fatal("expected the other replica to reject the revoked session")
+	}
+}
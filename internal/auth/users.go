@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// usersRefreshInterval bounds how often the users file is stat'ed for changes.
+const usersRefreshInterval = 2 * time.Second
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Email        string `json:"email,omitempty"`
+	Name         string `json:"name,omitempty"`
+	// Role is required. Entries without a known role are ignored, never given a default.
+	Role Role `json:"role"`
+}
+
+type usersFile struct {
+	Users []User `json:"users"`
+}
+
+// UserStore holds local dashboard users backed by a JSON file of bcrypt hashes.
+// Edits to the file are picked up without a restart.
+type UserStore struct {
+	path        string
+	mu          sync.RWMutex
+	users       map[string]User
+	modTime     time.Time
+	lastChecked time.Time
+}
+
+func NewUserStore(path string) (*UserStore, error) {
+	store := &UserStore{path: path, users: map[string]User{}}
+	if err := store.reload(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return store, err
+	}
+	return store, nil
+}
+
+func (s *UserStore) Authenticate(username, password string) (Identity, error) {
+	user, ok := s.Lookup(username)
+	if !ok {
+		// Compare against a fixed hash so unknown users cost the same as known ones.
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return Identity{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return Identity{}, ErrInvalidCredentials
+	}
+	return user.Identity(), nil
+}
+
+func (s *UserStore) Lookup(username string) (User, bool) {
+	s.refresh()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[normalizeUsername(username)]
+	return user, ok
+}
+
+// Put adds or replaces a user and rewrites the users file atomically.
+func (s *UserStore) Put(user User) error {
+	user.Username = normalizeUsername(user.Username)
+	if user.Username == "" {
+		return errors.New("username is required")
+	}
+	if user.PasswordHash == "" {
+		return errors.New("password hash is required")
+	}
+	role, ok := ParseRole(string(user.Role))
+	if !ok {
+		return fmt.Errorf("unknown role %q", user.Role)
+	}
+	user.Role = role
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make(map[string]User, len(s.users)+1)
+	for key, existing := range s.users {
+		next[key] = existing
+	}
+	next[user.Username] = user
+
+	if err := writeUsersFile(s.path, next); err != nil {
+		return err
+	}
+	s.users = next
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+func (s *UserStore) refresh() {
+	s.mu.RLock()
+	due := time.Since(s.lastChecked) >= usersRefreshInterval
+	s.mu.RUnlock()
+	if !due {
+		return
+	}
+	if err := s.reload(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("failed to reload studio users", "file", s.path, "error", err)
+	}
+}
+
+func (s *UserStore) reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastChecked = time.Now()
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			s.users = map[string]User{}
+			s.modTime = time.Time{}
+		}
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) && s.users != nil {
+		return nil
+	}
+
+	bytes, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var parsed usersFile
+	if err := json.Unmarshal(bytes, &parsed); err != nil {
+		return err
+	}
+
+	users := make(map[string]User, len(parsed.Users))
+	var invalid []error
+	for _, user := range parsed.Users {
+		key := normalizeUsername(user.Username)
+		if key == "" || user.PasswordHash == "" {
+			continue
+		}
+		role, ok := ParseRole(string(user.Role))
+		if !ok {
+			invalid = append(invalid, fmt.Errorf("user %q has unknown role %q and cannot sign in", key, user.Role))
+			continue
+		}
+		user.Username = key
+		user.Role = role
+		users[key] = user
+	}
+	s.users = users
+	s.modTime = info.ModTime()
+	return errors.Join(invalid...)
+}
+
+func writeUsersFile(path string, users map[string]User) error {
+	dir := filepath.Dir(path)
+	if dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	payload := usersFile{Users: make([]User, 0, len(users))}
+	for _, user := range users {
+		payload.Users = append(payload.Users, user)
+	}
+	sort.Slice(payload.Users, func(i, j int) bool {
+		return payload.Users[i].Username < payload.Users[j].Username
+	})
+
+	bytes, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, bytes, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (u User) Identity() Identity {
+	return Identity{
+		Username: u.Username,
+		Email:    u.Email,
+		Name:     u.Name,
+		Role:     u.Role,
+		Provider: ProviderLocal,
+	}
+}
+
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password is required")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("supabase-studio-go"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
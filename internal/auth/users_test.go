@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUserStoreAuthenticatesBcryptUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	store, err := NewUserStore(path)
+	if err != nil {
+		t.Fatalf("failed to create user store: %v", err)
+	}
+
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if err := store.Put(User{Username: "Alice", PasswordHash: hash, Email: "alice@example.com", Role: RoleDeveloper}); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+
+	identity, err := store.Authenticate("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("expected valid credentials to authenticate, got %v", err)
+	}
+	if identity.Username != "alice" || identity.Email != "alice@example.com" {
+		t.Fatalf("unexpected identity %#v", identity)
+	}
+
+	if _, err := store.Authenticate("alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials for wrong password, got %v", err)
+	}
+	if _, err := store.Authenticate("bob", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials for unknown user, got %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected users file to be written: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected users file mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestUserStoreReloadsUsersFromDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if err := writeUsersFile(path, map[string]User{"carol": {Username: "carol", PasswordHash: hash, Role: RoleViewer}}); err != nil {
+		t.Fatalf("failed to write users file: %v", err)
+	}
+
+	store, err := NewUserStore(path)
+	if err != nil {
+		t.Fatalf("failed to load users: %v", err)
+	}
+	if _, ok := store.Lookup("carol"); !ok {
+		t.Fatalf("expected carol to be loaded")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"users":[]}`), 0o600); err != nil {
+		t.Fatalf("failed to rewrite users file: %v", err)
+	}
+	if err := store.reload(); err != nil {
+		t.Fatalf("failed to reload users: %v", err)
+	}
+	if _, ok := store.Lookup("carol"); ok {
+		t.Fatalf("expected carol to be removed after reload")
+	}
+}
+
+func TestUserStoreRejectsUnknownRoles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if err := writeUsersFile(path, map[string]User{
+		"dave": {Username: "dave", PasswordHash: hash, Role: "amdin"},
+		"erin": {Username: "erin", PasswordHash: hash},
+		"fred": {Username: "fred", PasswordHash: hash, Role: RoleDeveloper},
+	}); err != nil {
+		t.Fatalf("failed to write users file: %v", err)
+	}
+
+	store, err := NewUserStore(path)
+	if err == nil || !strings.Contains(err.Error(), `"amdin"`) {
+		t.Fatalf("expected an error naming the unknown role, got %v", err)
+	}
+	for _, username := range []string{"dave", "erin"} {
+		if _, ok := store.Lookup(username); ok {
+			t.Errorf("expected %s to be rejected", username)
+		}
+	}
+	if user, ok := store.Lookup("fred"); !ok || user.Role != RoleDeveloper {
+		t.Fatalf("expected fred to keep the developer role, got %+v, %v", user, ok)
+	}
+	if err := store.Put(User{Username: "gina", PasswordHash: hash}); err == nil {
+		t.Fatal("expected Put without a role to fail")
+	}
+}
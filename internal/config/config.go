@@ -2,17 +2,51 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	ListenAddress string
-	BasePath      string
-	IsPlatform    bool
-	StateFilePath string
+	ListenAddress    string
+	ListenSocketMode string
+	BasePath         string
+	IsPlatform       bool
+	StateFilePath    string
+
+	// StateBackend stores dashboard settings in StateFilePath ("file") or in the studio
+	// schema of StateDatabaseURL ("postgres"), which defaults to the default project's
+	// database.
+	StateBackend     string
+	StateDatabaseURL string
+
+	// StateKeyFile holds the master key that encrypts the saved state. Documents encrypted
+	// under StatePreviousKeyFiles can still be read, so the key can be rotated.
+	StateKeyFile          string
+	StatePreviousKeyFiles []string
+
+	// FrontendDir serves the frontend from disk, falling back to the embedded bundle for
+	// files it does not contain. Its directories are checked for new or renamed pages every
+	// FrontendRescanInterval.
+	FrontendDir            string
+	FrontendRescanInterval time.Duration
+
+	// PublicEnvKeys are NEXT_PUBLIC_* variables exposed to the browser in addition to the
+	// built-in list. PublicEnv holds the values of both, from the environment or the file.
+	PublicEnvKeys []string
+	PublicEnv     map[string]string
+
+	// Production refuses to start with insecure defaults such as the sample pg-meta key.
+	Production bool
+
+	TLSCertFile        string
+	TLSKeyFile         string
+	TLSMinVersion      string
+	TLSClientCAFile    string
+	TLSRedirectAddress string
 
 	SupabaseURL        string
 	SupabasePublicURL  string
@@ -29,12 +63,26 @@ type Config struct {
 	PostgresUserReadWrite string
 	PostgresUserReadOnly  string
 
+	// PostgresDirect runs SQL over pooled Postgres connections instead of through pg-meta's
+	// query endpoint. PostgresMaxConns bounds each project's pool per role, and
+	// PostgresStatementTimeout each statement.
+	PostgresDirect           bool
+	PostgresMaxConns         int
+	PostgresStatementTimeout time.Duration
+
 	LogflareURL   string
 	LogflareToken string
 
 	SupportAPIURL string
 	SupportAPIKey string
 
+	OpenAIAPIKey string
+	OpenAIAPIURL string
+	OpenAIModels string
+
+	StatusPageID     string
+	StatusPageAPIKey string
+
 	EdgeFunctionsFolder string
 	SnippetsFolder      string
 
@@ -46,53 +94,212 @@ type Config struct {
 	DefaultProjectDiskSizeGB int
 
 	AuthJWTSecret string
+
+	// Projects are the projects besides the default one; see Project and AllProjects.
+	Projects []Project
+
+	StudioUsersFile     string
+	StudioSessionSecret string
+	StudioSessionTTL    time.Duration
+
+	StudioOIDCIssuer        string
+	StudioOIDCClientID      string
+	StudioOIDCClientSecret  string
+	StudioOIDCRedirectURL   string
+	StudioOIDCScopes        []string
+	StudioOIDCGroupsClaim   string
+	StudioOIDCAllowedGroups []string
+
+	StudioOIDCAdminGroups     []string
+	StudioOIDCDeveloperGroups []string
+	StudioOIDCDefaultRole     string
+
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSAllowCredentials bool
+
+	// CSRFTrustedOrigins may send unsafe requests besides the dashboard's own origin and the
+	// CORS origins.
+	CSRFTrustedOrigins []string
+
+	// CSPMode is enforce, report-only or off. The CSP*Sources lists extend the built-in
+	// policy directives.
+	CSPMode           string
+	CSPFrameAncestors []string
+	CSPScriptSources  []string
+	CSPConnectSources []string
+	CSPImageSources   []string
+	CSPStyleSources   []string
+	CSPFontSources    []string
+	CSPFrameSources   []string
+
+	AuditLogDir       string
+	AuditLogMaxSizeMB int
+	AuditLogMaxAge    time.Duration
+	AuditLogMaxFiles  int
+
+	MetricsToken string
+
+	// Requests per client and minute; 0 disables the limit.
+	RateLimitAI    int
+	RateLimitQuery int
+	// AI tokens per client and UTC day; 0 means unlimited.
+	AIDailyTokens int
+
+	ReadinessTimeout time.Duration
+	ShutdownTimeout  time.Duration
+	ShutdownDelay    time.Duration
+
+	LogFormat string
+	LogLevel  string
+
+	TracingEndpoint    string
+	TracingServiceName string
+	TracingSampleRatio float64
+
+	secretFiles map[string]*secretFile
 }
 
+// Load reads the configuration from the environment only. Use LoadFile to layer the
+// environment over a config file and to collect validation issues.
 func Load() Config {
-	return Config{
-		ListenAddress: envFirst("SUPABASE_STUDIO_GO_LISTEN", "STUDIO_GO_LISTEN"),
-		BasePath:      os.Getenv("NEXT_PUBLIC_BASE_PATH"),
-		IsPlatform:    strings.EqualFold(os.Getenv("NEXT_PUBLIC_IS_PLATFORM"), "true"),
-		StateFilePath: envOrAny(defaultStateFilePath(), "SUPABASE_STUDIO_GO_STATE_FILE", "STUDIO_GO_STATE_FILE"),
-
-		SupabaseURL:       os.Getenv("SUPABASE_URL"),
-		SupabasePublicURL: os.Getenv("SUPABASE_PUBLIC_URL"),
-		SupabaseAnonKey:   os.Getenv("SUPABASE_ANON_KEY"),
-		SupabaseServiceKey: envFirst(
+	cfg, _ := newSource(nil).load()
+	return cfg
+}
+
+func (s *source) load() (Config, []Issue) {
+	cfg := Config{
+		ListenAddress:    s.envFirst("SUPABASE_STUDIO_GO_LISTEN", "STUDIO_GO_LISTEN"),
+		ListenSocketMode: s.envOr("SUPABASE_STUDIO_GO_LISTEN_SOCKET_MODE", "0660"),
+		BasePath:         s.env("NEXT_PUBLIC_BASE_PATH"),
+		IsPlatform:       strings.EqualFold(s.env("NEXT_PUBLIC_IS_PLATFORM"), "true"),
+		StateFilePath:    s.envOrAny(defaultStateFilePath(), "SUPABASE_STUDIO_GO_STATE_FILE", "STUDIO_GO_STATE_FILE"),
+		StateBackend:     s.envOr("SUPABASE_STUDIO_GO_STATE_BACKEND", "file"),
+		StateDatabaseURL: s.env("SUPABASE_STUDIO_GO_STATE_DATABASE_URL"),
+
+		StateKeyFile:          s.env("SUPABASE_STUDIO_GO_STATE_KEY_FILE"),
+		StatePreviousKeyFiles: s.envOrList("SUPABASE_STUDIO_GO_STATE_PREVIOUS_KEY_FILES", nil),
+
+		FrontendDir:            s.env("SUPABASE_STUDIO_GO_FRONTEND_DIR"),
+		FrontendRescanInterval: s.envOrDuration("SUPABASE_STUDIO_GO_FRONTEND_RESCAN_INTERVAL", 30*time.Second),
+		PublicEnvKeys:          s.envOrList("SUPABASE_STUDIO_GO_PUBLIC_ENV_KEYS", nil),
+
+		Production: s.envOrBool("SUPABASE_STUDIO_GO_PRODUCTION", false),
+
+		TLSCertFile:        s.env("SUPABASE_STUDIO_GO_TLS_CERT_FILE"),
+		TLSKeyFile:         s.env("SUPABASE_STUDIO_GO_TLS_KEY_FILE"),
+		TLSMinVersion:      s.envOr("SUPABASE_STUDIO_GO_TLS_MIN_VERSION", "1.2"),
+		TLSClientCAFile:    s.env("SUPABASE_STUDIO_GO_TLS_CLIENT_CA_FILE"),
+		TLSRedirectAddress: s.env("SUPABASE_STUDIO_GO_TLS_REDIRECT_LISTEN"),
+
+		SupabaseURL:       s.env("SUPABASE_URL"),
+		SupabasePublicURL: s.env("SUPABASE_PUBLIC_URL"),
+		SupabaseAnonKey:   s.env("SUPABASE_ANON_KEY"),
+		SupabaseServiceKey: s.envFirst(
 			"SUPABASE_SERVICE_KEY",
 			"SUPABASE_SERVICE_ROLE_KEY",
 			"SERVICE_ROLE_KEY",
 			"SERVICE_KEY",
 		),
 
-		StudioPgMetaURL: os.Getenv("STUDIO_PG_META_URL"),
-		PgMetaCryptoKey: envOr("PG_META_CRYPTO_KEY", "SAMPLE_KEY"),
+		StudioPgMetaURL: s.env("STUDIO_PG_META_URL"),
+		PgMetaCryptoKey: s.envOr("PG_META_CRYPTO_KEY", samplePgMetaCryptoKey),
+
+		PostgresHost:          s.envOr("POSTGRES_HOST", "db"),
+		PostgresPort:          s.envOr("POSTGRES_PORT", "5432"),
+		PostgresDatabase:      s.envOr("POSTGRES_DB", "postgres"),
+		PostgresPassword:      s.envOr("POSTGRES_PASSWORD", samplePostgresPassword),
+		PostgresUserReadWrite: s.envOr("POSTGRES_USER_READ_WRITE", "supabase_admin"),
+		PostgresUserReadOnly:  s.envOr("POSTGRES_USER_READ_ONLY", "supabase_read_only_user"),
+
+		PostgresDirect:           s.envOrBool("SUPABASE_STUDIO_GO_POSTGRES_DIRECT", false),
+		PostgresMaxConns:         s.envOrInt("SUPABASE_STUDIO_GO_POSTGRES_MAX_CONNS", 4),
+		PostgresStatementTimeout: s.envOrDuration("SUPABASE_STUDIO_GO_POSTGRES_STATEMENT_TIMEOUT", 2*time.Minute),
+
+		LogflareURL:   s.env("LOGFLARE_URL"),
+		LogflareToken: s.env("LOGFLARE_PRIVATE_ACCESS_TOKEN"),
+
+		SupportAPIURL: s.env("NEXT_PUBLIC_SUPPORT_API_URL"),
+		SupportAPIKey: s.env("SUPPORT_SUPABASE_SECRET_KEY"),
+
+		OpenAIAPIKey: s.env("OPENAI_API_KEY"),
+		OpenAIAPIURL: s.env("OPENAI_API_URL"),
+		OpenAIModels: s.envFirst("OPENAI_MODELS", "OPENAI_MODEL"),
+
+		StatusPageID:     s.env("STATUSPAGE_PAGE_ID"),
+		StatusPageAPIKey: s.env("STATUSPAGE_API_KEY"),
+
+		EdgeFunctionsFolder: s.env("EDGE_FUNCTIONS_MANAGEMENT_FOLDER"),
+		SnippetsFolder:      s.env("SNIPPETS_MANAGEMENT_FOLDER"),
+
+		CustomerDomain: s.env("NEXT_PUBLIC_CUSTOMER_DOMAIN"),
+		APIDomain:      s.env("NEXT_PUBLIC_API_DOMAIN"),
+
+		DefaultOrganizationName:  s.envOr("DEFAULT_ORGANIZATION_NAME", "Default Organization"),
+		DefaultProjectName:       s.envOr("DEFAULT_PROJECT_NAME", "Default Project"),
+		DefaultProjectDiskSizeGB: s.envOrInt("DEFAULT_PROJECT_DISK_SIZE_GB", 8),
+
+		AuthJWTSecret: s.envOr("AUTH_JWT_SECRET", sampleAuthJWTSecret),
+
+		StudioUsersFile:     s.env("SUPABASE_STUDIO_GO_USERS_FILE"),
+		StudioSessionSecret: s.env("SUPABASE_STUDIO_GO_SESSION_SECRET"),
+		StudioSessionTTL:    s.envOrDuration("SUPABASE_STUDIO_GO_SESSION_TTL", 12*time.Hour),
+
+		StudioOIDCIssuer:        s.env("SUPABASE_STUDIO_GO_OIDC_ISSUER"),
+		StudioOIDCClientID:      s.env("SUPABASE_STUDIO_GO_OIDC_CLIENT_ID"),
+		StudioOIDCClientSecret:  s.env("SUPABASE_STUDIO_GO_OIDC_CLIENT_SECRET"),
+		StudioOIDCRedirectURL:   s.env("SUPABASE_STUDIO_GO_OIDC_REDIRECT_URL"),
+		StudioOIDCScopes:        s.envOrList("SUPABASE_STUDIO_GO_OIDC_SCOPES", []string{"openid", "email", "profile", "groups"}),
+		StudioOIDCGroupsClaim:   s.envOr("SUPABASE_STUDIO_GO_OIDC_GROUPS_CLAIM", "groups"),
+		StudioOIDCAllowedGroups: s.envOrList("SUPABASE_STUDIO_GO_OIDC_ALLOWED_GROUPS", nil),
 
-		PostgresHost:          envOr("POSTGRES_HOST", "db"),
-		PostgresPort:          envOr("POSTGRES_PORT", "5432"),
-		PostgresDatabase:      envOr("POSTGRES_DB", "postgres"),
-		PostgresPassword:      envOr("POSTGRES_PASSWORD", "postgres"),
-		PostgresUserReadWrite: envOr("POSTGRES_USER_READ_WRITE", "supabase_admin"),
-		PostgresUserReadOnly:  envOr("POSTGRES_USER_READ_ONLY", "supabase_read_only_user"),
+		StudioOIDCAdminGroups:     s.envOrList("SUPABASE_STUDIO_GO_OIDC_ADMIN_GROUPS", nil),
+		StudioOIDCDeveloperGroups: s.envOrList("SUPABASE_STUDIO_GO_OIDC_DEVELOPER_GROUPS", nil),
+		StudioOIDCDefaultRole:     s.envOr("SUPABASE_STUDIO_GO_OIDC_DEFAULT_ROLE", "viewer"),
 
-		LogflareURL:   os.Getenv("LOGFLARE_URL"),
-		LogflareToken: os.Getenv("LOGFLARE_PRIVATE_ACCESS_TOKEN"),
+		CORSAllowedOrigins:   s.envOrList("SUPABASE_STUDIO_GO_CORS_ALLOWED_ORIGINS", nil),
+		CORSAllowedMethods:   s.envOrList("SUPABASE_STUDIO_GO_CORS_ALLOWED_METHODS", []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}),
+		CORSAllowedHeaders:   s.envOrList("SUPABASE_STUDIO_GO_CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "apikey", "x-client-info"}),
+		CORSAllowCredentials: s.envOrBool("SUPABASE_STUDIO_GO_CORS_ALLOW_CREDENTIALS", false),
 
-		SupportAPIURL: os.Getenv("NEXT_PUBLIC_SUPPORT_API_URL"),
-		SupportAPIKey: os.Getenv("SUPPORT_SUPABASE_SECRET_KEY"),
+		CSRFTrustedOrigins: s.envOrList("SUPABASE_STUDIO_GO_CSRF_TRUSTED_ORIGINS", nil),
 
-		EdgeFunctionsFolder: os.Getenv("EDGE_FUNCTIONS_MANAGEMENT_FOLDER"),
-		SnippetsFolder:      os.Getenv("SNIPPETS_MANAGEMENT_FOLDER"),
+		CSPMode:           s.envOr("SUPABASE_STUDIO_GO_CSP_MODE", "report-only"),
+		CSPFrameAncestors: s.envOrList("SUPABASE_STUDIO_GO_CSP_FRAME_ANCESTORS", []string{"'none'"}),
+		CSPScriptSources:  s.envOrList("SUPABASE_STUDIO_GO_CSP_SCRIPT_SRC", nil),
+		CSPConnectSources: s.envOrList("SUPABASE_STUDIO_GO_CSP_CONNECT_SRC", nil),
+		CSPImageSources:   s.envOrList("SUPABASE_STUDIO_GO_CSP_IMG_SRC", nil),
+		CSPStyleSources:   s.envOrList("SUPABASE_STUDIO_GO_CSP_STYLE_SRC", nil),
+		CSPFontSources:    s.envOrList("SUPABASE_STUDIO_GO_CSP_FONT_SRC", nil),
+		CSPFrameSources:   s.envOrList("SUPABASE_STUDIO_GO_CSP_FRAME_SRC", nil),
 
-		CustomerDomain: os.Getenv("NEXT_PUBLIC_CUSTOMER_DOMAIN"),
-		APIDomain:      os.Getenv("NEXT_PUBLIC_API_DOMAIN"),
+		AuditLogDir:       s.env("SUPABASE_STUDIO_GO_AUDIT_DIR"),
+		AuditLogMaxSizeMB: s.envOrInt("SUPABASE_STUDIO_GO_AUDIT_MAX_SIZE_MB", 100),
+		AuditLogMaxAge:    s.envOrDuration("SUPABASE_STUDIO_GO_AUDIT_MAX_AGE", 24*time.Hour),
+		AuditLogMaxFiles:  s.envOrInt("SUPABASE_STUDIO_GO_AUDIT_MAX_FILES", 30),
 
-		DefaultOrganizationName:  envOr("DEFAULT_ORGANIZATION_NAME", "Default Organization"),
-		DefaultProjectName:       envOr("DEFAULT_PROJECT_NAME", "Default Project"),
-		DefaultProjectDiskSizeGB: envOrInt("DEFAULT_PROJECT_DISK_SIZE_GB", 8),
+		MetricsToken: s.env("SUPABASE_STUDIO_GO_METRICS_TOKEN"),
 
-		AuthJWTSecret: envOr("AUTH_JWT_SECRET", "super-secret-jwt-token-with-at-least-32-characters-long"),
+		RateLimitAI:    s.envOrInt("SUPABASE_STUDIO_GO_RATE_LIMIT_AI", 0),
+		RateLimitQuery: s.envOrInt("SUPABASE_STUDIO_GO_RATE_LIMIT_QUERY", 0),
+		AIDailyTokens:  s.envOrInt("SUPABASE_STUDIO_GO_AI_DAILY_TOKENS", 0),
+
+		ReadinessTimeout: s.envOrDuration("SUPABASE_STUDIO_GO_READY_TIMEOUT", 2*time.Second),
+		ShutdownTimeout:  s.envOrDuration("SUPABASE_STUDIO_GO_SHUTDOWN_TIMEOUT", 30*time.Second),
+		ShutdownDelay:    s.envOrDuration("SUPABASE_STUDIO_GO_SHUTDOWN_DELAY", 0),
+
+		LogFormat: s.envOr("SUPABASE_STUDIO_GO_LOG_FORMAT", "json"),
+		LogLevel:  s.envOr("SUPABASE_STUDIO_GO_LOG_LEVEL", "info"),
+
+		TracingEndpoint:    s.envFirst("SUPABASE_STUDIO_GO_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"),
+		TracingServiceName: s.envOrAny("supabase-studio-go", "OTEL_SERVICE_NAME"),
+		TracingSampleRatio: s.envOrFloat("SUPABASE_STUDIO_GO_TRACE_SAMPLE_RATIO", 1),
 	}
+	s.loadSecrets(&cfg)
+	s.loadProjects(&cfg)
+	s.loadPublicEnv(&cfg)
+	return cfg, s.validate(cfg)
 }
 
 func defaultStateFilePath() string {
@@ -124,47 +331,131 @@ func migrateLegacyStateFile(targetPath string) {
 
 	dir := filepath.Dir(targetPath)
 	if dir != "" && dir != "." {
-		if err := os.MkdirAll(dir, 0o755); err != nil {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
 			return
 		}
 	}
 
-	_ = os.WriteFile(targetPath, bytes, 0o644)
+	// Still plaintext; the state store encrypts it when it is first loaded with a key.
+	_ = os.WriteFile(targetPath, bytes, 0o600)
+}
+
+// source resolves settings by key. Environment variables take precedence over values from
+// the config file; every lookup is remembered so that unknown file keys can be reported.
+type source struct {
+	file   map[string]string
+	known  map[string]bool
+	issues []Issue
 }
 
-func envOr(key, fallback string) string {
+func newSource(file map[string]string) *source {
+	return &source{file: file, known: map[string]bool{FileEnv: true}}
+}
+
+func (s *source) env(key string) string {
+	s.known[key] = true
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
+	return s.file[key]
+}
+
+func (s *source) invalid(key, value, expected string) {
+	s.issues = append(s.issues, Issue{Key: key, Message: fmt.Sprintf("%q is not %s, using the default", value, expected)})
+}
+
+func (s *source) envOr(key, fallback string) string {
+	if value := s.env(key); value != "" {
+		return value
+	}
 	return fallback
 }
 
-func envFirst(keys ...string) string {
+func (s *source) envFirst(keys ...string) string {
 	for _, key := range keys {
-		if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		if value := strings.TrimSpace(s.env(key)); value != "" {
 			return value
 		}
 	}
 	return ""
 }
 
-func envOrAny(fallback string, keys ...string) string {
-	if value := envFirst(keys...); value != "" {
+func (s *source) envOrAny(fallback string, keys ...string) string {
+	if value := s.envFirst(keys...); value != "" {
 		return value
 	}
 	return fallback
 }
 
-func envOrInt(key string, fallback int) int {
-	value := strings.TrimSpace(os.Getenv(key))
+func (s *source) envOrInt(key string, fallback int) int {
+	value := strings.TrimSpace(s.env(key))
 	if value == "" {
 		return fallback
 	}
 
 	parsed, err := strconv.Atoi(value)
 	if err != nil {
+		s.invalid(key, value, "an integer")
 		return fallback
 	}
 
 	return parsed
 }
+
+func (s *source) envOrFloat(key string, fallback float64) float64 {
+	value := strings.TrimSpace(s.env(key))
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		s.invalid(key, value, "a number")
+		return fallback
+	}
+
+	return parsed
+}
+
+func (s *source) envOrBool(key string, fallback bool) bool {
+	value := strings.TrimSpace(s.env(key))
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		s.invalid(key, value, "a boolean")
+		return fallback
+	}
+
+	return parsed
+}
+
+func (s *source) envOrDuration(key string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(s.env(key))
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		s.invalid(key, value, "a positive duration such as 30s")
+		return fallback
+	}
+
+	return parsed
+}
+
+func (s *source) envOrList(key string, fallback []string) []string {
+	var values []string
+	for _, value := range strings.Split(s.env(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return fallback
+	}
+	return values
+}
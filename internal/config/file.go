@@ -0,0 +1,121 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable pointing at the optional config file.
+const FileEnv = "SUPABASE_STUDIO_GO_CONFIG_FILE"
+
+// LoadFile reads the config file at path (YAML, TOML or JSON, by extension) and layers the
+// environment over it. An empty path loads from the environment only. Issues describe
+// values that were ignored or are unsafe; they do not prevent loading.
+func LoadFile(path string) (Config, []Issue, error) {
+	var values map[string]string
+	if strings.TrimSpace(path) != "" {
+		var err error
+		values, err = readFile(path)
+		if err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	cfg, issues := newSource(values).load()
+	return cfg, issues, nil
+}
+
+// readFile flattens the file into environment-style keys: nested tables are joined with
+// underscores and upper-cased, so `postgres: {host: db}` sets POSTGRES_HOST, and lists
+// become comma-separated values.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (use .yaml, .toml or .json)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	if err := flatten(values, "", raw); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flatten(out map[string]string, prefix string, raw map[string]any) error {
+	for key, value := range raw {
+		name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		if nested, ok := value.(map[string]any); ok {
+			if err := flatten(out, name, nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if list, ok := value.([]any); ok {
+			items := make([]string, 0, len(list))
+			for _, item := range list {
+				text, err := scalarString(name, item)
+				if err != nil {
+					return err
+				}
+				items = append(items, text)
+			}
+			out[name] = strings.Join(items, ",")
+			continue
+		}
+
+		text, err := scalarString(name, value)
+		if err != nil {
+			return err
+		}
+		out[name] = text
+	}
+	return nil
+}
+
+func scalarString(key string, value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("%s: unsupported value of type %T", key, value)
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFileLayersEnvironmentOverFile(t *testing.T) {
+	files := map[string]string{
+		"studio.yaml": "supabase_url: http://kong:8000\npostgres:\n  host: file-db\nsupabase_studio_go:\n  oidc:\n    scopes: [openid, email]\ndefault_project_disk_size_gb: 16\n",
+		"studio.toml": "supabase_url = \"http://kong:8000\"\ndefault_project_disk_size_gb = 16\n[postgres]\nhost = \"file-db\"\n[supabase_studio_go.oidc]\nscopes = [\"openid\", \"email\"]\n",
+		"studio.json": `{"supabase_url":"http://kong:8000","postgres":{"host":"file-db"},"supabase_studio_go":{"oidc":{"scopes":["openid","email"]}},"default_project_disk_size_gb":16}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("SUPABASE_URL", "")
+			t.Setenv("POSTGRES_HOST", "env-db")
+
+			cfg, _, err := LoadFile(writeConfigFile(t, name, content))
+			if err != nil {
+				t.Fatalf("LoadFile: %v", err)
+			}
+			if cfg.SupabaseURL != "http://kong:8000" {
+				t.Fatalf("expected file value for SUPABASE_URL, got %q", cfg.SupabaseURL)
+			}
+			if cfg.PostgresHost != "env-db" {
+				t.Fatalf("expected environment to win for POSTGRES_HOST, got %q", cfg.PostgresHost)
+			}
+			if cfg.DefaultProjectDiskSizeGB != 16 {
+				t.Fatalf("expected disk size 16, got %d", cfg.DefaultProjectDiskSizeGB)
+			}
+			if len(cfg.StudioOIDCScopes) != 2 || cfg.StudioOIDCScopes[1] != "email" {
+				t.Fatalf("expected scopes from file list, got %v", cfg.StudioOIDCScopes)
+			}
+		})
+	}
+}
+
+func TestLoadFileReportsIssues(t *testing.T) {
+	t.Setenv("PG_META_CRYPTO_KEY", "")
+	t.Setenv("AUTH_JWT_SECRET", "a-real-secret")
+	t.Setenv("POSTGRES_PASSWORD", "")
+
+	path := writeConfigFile(t, "studio.yaml", "supabase_url: kong:8000\nlogflare_url: http://logflare:4000\nsupabase_studio_go:\n  audit_max_size_mb: lots\n  unknown_option: true\npostgres:\n  password: s3cret\n")
+	_, issues, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	byKey := map[string]Issue{}
+	for _, issue := range issues {
+		byKey[issue.Key] = issue
+	}
+	for _, key := range []string{"SUPABASE_URL", "SUPABASE_STUDIO_GO_AUDIT_MAX_SIZE_MB", "SUPABASE_STUDIO_GO_UNKNOWN_OPTION", "PG_META_CRYPTO_KEY"} {
+		if _, ok := byKey[key]; !ok {
+			t.Fatalf("expected an issue for %s, got %v", key, issues)
+		}
+	}
+	for _, key := range []string{"LOGFLARE_URL", "AUTH_JWT_SECRET", "POSTGRES_PASSWORD"} {
+		if issue, ok := byKey[key]; ok {
+			t.Fatalf("unexpected issue %s", issue)
+		}
+	}
+	if insecure := Insecure(issues); len(insecure) != 1 || insecure[0].Key != "PG_META_CRYPTO_KEY" {
+		t.Fatalf("expected only the sample pg-meta key to be insecure, got %v", insecure)
+	}
+}
+
+func TestLoadFileRejectsUnsupportedFormat(t *testing.T) {
+	if _, _, err := LoadFile(writeConfigFile(t, "studio.ini", "a=b")); err == nil {
+		t.Fatal("expected an error for an unsupported extension")
+	}
+}
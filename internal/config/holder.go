@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Holder keeps the live configuration and swaps it atomically on reload, so requests in
+// flight keep the snapshot they started with and new requests see the new one.
+type Holder struct {
+	path    string
+	current atomic.Pointer[Config]
+	mu      sync.Mutex
+}
+
+// NewHolder holds cfg. Reload re-reads path (which may be empty) and the environment.
+func NewHolder(cfg Config, path string) *Holder {
+	holder := &Holder{path: path}
+	holder.current.Store(&cfg)
+	return holder
+}
+
+// Get returns the current configuration, with file-based secrets re-read if they changed.
+func (h *Holder) Get() Config {
+	return h.current.Load().Current()
+}
+
+// Change is a setting that differs after a reload. Secrets are never reported.
+// RestartRequired marks settings that are only read at startup, so the new value has no
+// effect until the process restarts.
+type Change struct {
+	Key             string `json:"key"`
+	Old             string `json:"old"`
+	New             string `json:"new"`
+	RestartRequired bool   `json:"restart_required,omitempty"`
+}
+
+// restartFields are read once at startup: by main for the listeners, logging and tracing,
+// and by the server and router when they build their middleware, sign-in, state store,
+// audit log and rate limits.
+var restartFields = map[string]bool{
+	"ListenAddress": true, "ListenSocketMode": true, "BasePath": true, "IsPlatform": true,
+	"StateFilePath": true, "StateBackend": true, "StateDatabaseURL": true,
+	"StateKeyFile": true, "StatePreviousKeyFiles": true,
+	"FrontendDir": true, "FrontendRescanInterval": true,
+	"TLSCertFile": true, "TLSKeyFile": true, "TLSMinVersion": true,
+	"TLSClientCAFile": true, "TLSRedirectAddress": true,
+	"StudioUsersFile": true, "StudioSessionTTL": true,
+	"StudioOIDCIssuer": true, "StudioOIDCClientID": true, "StudioOIDCRedirectURL": true,
+	"StudioOIDCScopes": true, "StudioOIDCGroupsClaim": true, "StudioOIDCAllowedGroups": true,
+	"StudioOIDCAdminGroups": true, "StudioOIDCDeveloperGroups": true, "StudioOIDCDefaultRole": true,
+	"CORSAllowedOrigins": true, "CORSAllowedMethods": true, "CORSAllowedHeaders": true,
+	"CORSAllowCredentials": true, "CSRFTrustedOrigins": true,
+	"CSPMode": true, "CSPFrameAncestors": true, "CSPScriptSources": true, "CSPConnectSources": true,
+	"CSPImageSources": true, "CSPStyleSources": true, "CSPFontSources": true, "CSPFrameSources": true,
+	"AuditLogDir": true, "AuditLogMaxSizeMB": true, "AuditLogMaxAge": true, "AuditLogMaxFiles": true,
+	"RateLimitAI": true, "RateLimitQuery": true, "AIDailyTokens": true,
+	"LogFormat": true, "LogLevel": true,
+	"TracingEndpoint": true, "TracingServiceName": true, "TracingSampleRatio": true,
+}
+
+// Reload re-reads the config file and swaps the configuration in. Environment variables
+// cannot change for a running process, so in practice this picks up edits to the file.
+// A configuration that production mode would refuse to start with is rejected.
+func (h *Holder) Reload() ([]Change, []Issue, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	next, issues, err := LoadFile(h.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if insecure := Insecure(issues); next.Production && len(insecure) > 0 {
+		return nil, issues, errors.New("refusing to reload: insecure defaults in use in production mode")
+	}
+
+	previous := h.current.Load()
+	changes := diff(previous, &next)
+	h.current.Store(&next)
+
+	var applied, restart []any
+	for _, change := range changes {
+		attr := slog.Group(change.Key, "old", change.Old, "new", change.New)
+		if change.RestartRequired {
+			restart = append(restart, attr)
+		} else {
+			applied = append(applied, attr)
+		}
+	}
+	slog.Info("configuration reloaded", slog.Int("changed", len(applied)), slog.Group("changes", applied...))
+	if len(restart) > 0 {
+		slog.Warn("configuration changes need a restart to take effect", slog.Group("changes", restart...))
+	}
+	for _, issue := range issues {
+		slog.Warn("configuration issue", "key", issue.Key, "problem", issue.Message)
+	}
+	return changes, issues, nil
+}
+
+func diff(previous, next *Config) []Change {
+	secret := map[any]bool{}
+	for _, field := range secretFields {
+		secret[reflect.ValueOf(field.field(next)).UnsafePointer()] = true
+	}
+
+	var changes []Change
+	before := reflect.ValueOf(previous).Elem()
+	after := reflect.ValueOf(next).Elem()
+	for i := 0; i < after.NumField(); i++ {
+		field := after.Type().Field(i)
+		if !field.IsExported() || secret[after.Field(i).Addr().UnsafePointer()] || field.Name == "Projects" {
+			continue
+		}
+		if reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			continue
+		}
+		changes = append(changes, Change{
+			Key:             field.Name,
+			Old:             fmt.Sprint(before.Field(i).Interface()),
+			New:             fmt.Sprint(after.Field(i).Interface()),
+			RestartRequired: restartFields[field.Name],
+		})
+	}
+	return append(changes, diffProjects(previous.Projects, next.Projects)...)
+}
+
+// diffProjects reports added and removed projects, and changed settings other than secrets.
+func diffProjects(previous, next []Project) []Change {
+	before := map[string]Project{}
+	for _, project := range previous {
+		before[project.Ref] = project
+	}
+
+	var changes []Change
+	for _, project := range next {
+		old, existed := before[project.Ref]
+		delete(before, project.Ref)
+		if !existed {
+			changes = append(changes, Change{Key: "Projects." + project.Ref, Old: "", New: "added"})
+			continue
+		}
+		if old.DiskSizeGB != project.DiskSizeGB {
+			changes = append(changes, Change{Key: "Projects." + project.Ref + ".DISK_SIZE_GB", Old: fmt.Sprint(old.DiskSizeGB), New: fmt.Sprint(project.DiskSizeGB)})
+		}
+		for _, setting := range projectSettings {
+			if setting.secret || *setting.field(&old) == *setting.field(&project) {
+				continue
+			}
+			changes = append(changes, Change{Key: "Projects." + project.Ref + "." + setting.key, Old: *setting.field(&old), New: *setting.field(&project)})
+		}
+	}
+	for _, ref := range slices.Sorted(maps.Keys(before)) {
+		changes = append(changes, Change{Key: "Projects." + ref, Old: "configured", New: "removed"})
+	}
+	return changes
+}
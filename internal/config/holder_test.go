@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestHolderReloadSwapsConfigAndReportsNonSecretChanges(t *testing.T) {
+	t.Setenv("DEFAULT_ORGANIZATION_NAME", "")
+	t.Setenv("LOGFLARE_URL", "")
+	t.Setenv("AUTH_JWT_SECRET", "")
+
+	path := writeConfigFile(t, "studio.yaml", "default_organization_name: Acme\nauth_jwt_secret: first-secret-that-is-long-enough\n")
+	cfg, _, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	holder := NewHolder(cfg, path)
+
+	if err := os.WriteFile(path, []byte("default_organization_name: Globex\nlogflare_url: http://logflare:4000\nauth_jwt_secret: second-secret-that-is-long-enough\n"), 0o600); err != nil {
+		t.Fatalf("failed to rewrite config file: %v", err)
+	}
+	changes, _, err := holder.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if got := holder.Get(); got.DefaultOrganizationName != "Globex" || got.AuthJWTSecret != "second-secret-that-is-long-enough" {
+		t.Fatalf("expected reloaded values, got %q / %q", got.DefaultOrganizationName, got.AuthJWTSecret)
+	}
+	want := map[string]Change{
+		"DefaultOrganizationName": {Key: "DefaultOrganizationName", Old: "Acme", New: "Globex"},
+		"LogflareURL":             {Key: "LogflareURL", Old: "", New: "http://logflare:4000"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for _, change := range changes {
+		if want[change.Key] != change {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	}
+}
+
+func TestHolderReloadMarksStartupOnlySettingsAsRestartRequired(t *testing.T) {
+	t.Setenv("DEFAULT_ORGANIZATION_NAME", "")
+	t.Setenv("SUPABASE_STUDIO_GO_LOG_FORMAT", "")
+
+	path := writeConfigFile(t, "studio.yaml", "default_organization_name: Acme\nsupabase_studio_go_log_format: json\n")
+	cfg, _, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	holder := NewHolder(cfg, path)
+
+	if err := os.WriteFile(path, []byte("default_organization_name: Globex\nsupabase_studio_go_log_format: text\n"), 0o600); err != nil {
+		t.Fatalf("failed to rewrite config file: %v", err)
+	}
+	changes, _, err := holder.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	want := map[string]Change{
+		"DefaultOrganizationName": {Key: "DefaultOrganizationName", Old: "Acme", New: "Globex"},
+		"LogFormat":               {Key: "LogFormat", Old: "json", New: "text", RestartRequired: true},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for _, change := range changes {
+		if want[change.Key] != change {
+			t.Fatalf("unexpected change %+v", change)
+		}
+	}
+}
+
+func TestRestartFieldsNameConfigFields(t *testing.T) {
+	config := reflect.TypeFor[Config]()
+	for name := range restartFields {
+		if _, ok := config.FieldByName(name); !ok {
+			t.Errorf("restartFields names %s, which is not a Config field", name)
+		}
+	}
+}
+
+func TestHolderReloadKeepsConfigOnInsecureProductionFile(t *testing.T) {
+	t.Setenv("DEFAULT_ORGANIZATION_NAME", "")
+	t.Setenv("SUPABASE_STUDIO_GO_PRODUCTION", "true")
+	t.Setenv("PG_META_CRYPTO_KEY", "real-key")
+	t.Setenv("AUTH_JWT_SECRET", "real-secret-that-is-long-enough")
+	t.Setenv("POSTGRES_PASSWORD", "real-password")
+
+	path := writeConfigFile(t, "studio.yaml", "default_organization_name: Acme\n")
+	cfg, _, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	holder := NewHolder(cfg, path)
+
+	t.Setenv("PG_META_CRYPTO_KEY", "")
+	if err := os.WriteFile(path, []byte("default_organization_name: Globex\n"), 0o600); err != nil {
+		t.Fatalf("failed to rewrite config file: %v", err)
+	}
+	if _, _, err := holder.Reload(); err == nil {
+		t.Fatal("expected reload with the sample pg-meta key to be rejected in production mode")
+	}
+	if got := holder.Get().DefaultOrganizationName; got != "Acme" {
+		t.Fatalf("expected previous configuration to stay in place, got %q", got)
+	}
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultProjectRef is the project built from the top-level settings. It always exists, as
+// the self-hosted frontend addresses it directly.
+const DefaultProjectRef = "default"
+
+var projectRefPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,39}$`)
+
+// Project is one Supabase stack served by the dashboard.
+type Project struct {
+	Ref        string
+	Name       string
+	DiskSizeGB int
+
+	SupabaseURL        string
+	SupabasePublicURL  string
+	SupabaseAnonKey    string
+	SupabaseServiceKey string
+
+	StudioPgMetaURL string
+	PgMetaCryptoKey string
+
+	PostgresHost          string
+	PostgresPort          string
+	PostgresDatabase      string
+	PostgresPassword      string
+	PostgresUserReadWrite string
+	PostgresUserReadOnly  string
+
+	LogflareURL   string
+	LogflareToken string
+
+	EdgeFunctionsFolder string
+	SnippetsFolder      string
+
+	AuthJWTSecret string
+}
+
+// projectSettings maps the per-project setting names, which reuse the top-level variable
+// names, to their fields.
+var projectSettings = []struct {
+	key    string
+	secret bool
+	field  func(*Project) *string
+}{
+	{"NAME", false, func(p *Project) *string { return &p.Name }},
+	{"SUPABASE_URL", false, func(p *Project) *string { return &p.SupabaseURL }},
+	{"SUPABASE_PUBLIC_URL", false, func(p *Project) *string { return &p.SupabasePublicURL }},
+	{"SUPABASE_ANON_KEY", true, func(p *Project) *string { return &p.SupabaseAnonKey }},
+	{"SUPABASE_SERVICE_KEY", true, func(p *Project) *string { return &p.SupabaseServiceKey }},
+	{"STUDIO_PG_META_URL", false, func(p *Project) *string { return &p.StudioPgMetaURL }},
+	{"PG_META_CRYPTO_KEY", true, func(p *Project) *string { return &p.PgMetaCryptoKey }},
+	{"POSTGRES_HOST", false, func(p *Project) *string { return &p.PostgresHost }},
+	{"POSTGRES_PORT", false, func(p *Project) *string { return &p.PostgresPort }},
+	{"POSTGRES_DB", false, func(p *Project) *string { return &p.PostgresDatabase }},
+	{"POSTGRES_PASSWORD", true, func(p *Project) *string { return &p.PostgresPassword }},
+	{"POSTGRES_USER_READ_WRITE", false, func(p *Project) *string { return &p.PostgresUserReadWrite }},
+	{"POSTGRES_USER_READ_ONLY", false, func(p *Project) *string { return &p.PostgresUserReadOnly }},
+	{"LOGFLARE_URL", false, func(p *Project) *string { return &p.LogflareURL }},
+	{"LOGFLARE_PRIVATE_ACCESS_TOKEN", true, func(p *Project) *string { return &p.LogflareToken }},
+	{"EDGE_FUNCTIONS_MANAGEMENT_FOLDER", false, func(p *Project) *string { return &p.EdgeFunctionsFolder }},
+	{"SNIPPETS_MANAGEMENT_FOLDER", false, func(p *Project) *string { return &p.SnippetsFolder }},
+	{"AUTH_JWT_SECRET", true, func(p *Project) *string { return &p.AuthJWTSecret }},
+}
+
+// projectKey is the variable holding a setting of an additional project, for example
+// SUPABASE_STUDIO_GO_PROJECT_STAGING_SUPABASE_URL.
+func projectKey(ref, setting string) string {
+	return "SUPABASE_STUDIO_GO_PROJECT_" + strings.ToUpper(strings.ReplaceAll(ref, "-", "_")) + "_" + setting
+}
+
+// defaultProject builds the default project from the top-level settings.
+func (c Config) defaultProject() Project {
+	return Project{
+		Ref:                   DefaultProjectRef,
+		Name:                  c.DefaultProjectName,
+		DiskSizeGB:            c.DefaultProjectDiskSizeGB,
+		SupabaseURL:           c.SupabaseURL,
+		SupabasePublicURL:     c.SupabasePublicURL,
+		SupabaseAnonKey:       c.SupabaseAnonKey,
+		SupabaseServiceKey:    c.SupabaseServiceKey,
+		StudioPgMetaURL:       c.StudioPgMetaURL,
+		PgMetaCryptoKey:       c.PgMetaCryptoKey,
+		PostgresHost:          c.PostgresHost,
+		PostgresPort:          c.PostgresPort,
+		PostgresDatabase:      c.PostgresDatabase,
+		PostgresPassword:      c.PostgresPassword,
+		PostgresUserReadWrite: c.PostgresUserReadWrite,
+		PostgresUserReadOnly:  c.PostgresUserReadOnly,
+		LogflareURL:           c.LogflareURL,
+		LogflareToken:         c.LogflareToken,
+		EdgeFunctionsFolder:   c.EdgeFunctionsFolder,
+		SnippetsFolder:        c.SnippetsFolder,
+		AuthJWTSecret:         c.AuthJWTSecret,
+	}
+}
+
+// inherit fills the settings p leaves unset from the default project. It runs on lookup
+// rather than on load, so inherited secrets follow their files when these change.
+func (p Project) inherit(base Project) Project {
+	for _, setting := range projectSettings {
+		if field := setting.field(&p); *field == "" {
+			*field = *setting.field(&base)
+		}
+	}
+	if p.DiskSizeGB <= 0 {
+		p.DiskSizeGB = base.DiskSizeGB
+	}
+	return p
+}
+
+// Project returns the project with the given ref. An empty ref is the default project.
+func (c Config) Project(ref string) (Project, bool) {
+	if ref == "" || ref == DefaultProjectRef {
+		return c.defaultProject(), true
+	}
+	for _, project := range c.Projects {
+		if project.Ref == ref {
+			return project.inherit(c.defaultProject()), true
+		}
+	}
+	return Project{}, false
+}
+
+// AllProjects returns the default project followed by the additional ones.
+func (c Config) AllProjects() []Project {
+	base := c.defaultProject()
+	projects := []Project{base}
+	for _, project := range c.Projects {
+		projects = append(projects, project.inherit(base))
+	}
+	return projects
+}
+
+// loadProjects reads the additional projects listed in SUPABASE_STUDIO_GO_PROJECTS. Settings
+// a project leaves unset are inherited from the default project, except for the management
+// folders: those default to a sibling of the default project's folder suffixed with the ref,
+// such as snippets-staging, so that projects never share files.
+func (s *source) loadProjects(cfg *Config) {
+	base := cfg.defaultProject()
+	for _, ref := range s.envOrList("SUPABASE_STUDIO_GO_PROJECTS", nil) {
+		switch {
+		case !projectRefPattern.MatchString(ref):
+			s.issues = append(s.issues, Issue{Key: "SUPABASE_STUDIO_GO_PROJECTS", Message: fmt.Sprintf("project ref %q must be lowercase letters, digits and dashes", ref)})
+			continue
+		case ref == DefaultProjectRef || slices.ContainsFunc(cfg.Projects, func(p Project) bool { return p.Ref == ref }):
+			s.issues = append(s.issues, Issue{Key: "SUPABASE_STUDIO_GO_PROJECTS", Message: fmt.Sprintf("project ref %q is listed twice", ref)})
+			continue
+		}
+
+		project := Project{Ref: ref, Name: ref}
+		if base.EdgeFunctionsFolder != "" {
+			project.EdgeFunctionsFolder = filepath.Clean(base.EdgeFunctionsFolder) + "-" + ref
+		}
+		if base.SnippetsFolder != "" {
+			project.SnippetsFolder = filepath.Clean(base.SnippetsFolder) + "-" + ref
+		}
+		for _, setting := range projectSettings {
+			key := projectKey(ref, setting.key)
+			value := s.env(key)
+			if setting.secret {
+				if path := strings.TrimSpace(s.env(key + "_FILE")); path != "" {
+					file, err := openSecretFile(path)
+					if err != nil {
+						s.issues = append(s.issues, Issue{Key: key + "_FILE", Message: fmt.Sprintf("cannot read secret file: %v", err)})
+						continue
+					}
+					cfg.secretFiles[key] = file
+					value = file.value
+				}
+			}
+			if value != "" {
+				*setting.field(&project) = value
+			}
+		}
+		project.DiskSizeGB = s.envOrInt(projectKey(ref, "DISK_SIZE_GB"), 0)
+		cfg.Projects = append(cfg.Projects, project)
+	}
+}
+
+// currentProjects returns a copy of projects with their file-based secrets re-read.
+func (c Config) currentProjects() []Project {
+	if len(c.Projects) == 0 {
+		return c.Projects
+	}
+	projects := slices.Clone(c.Projects)
+	for i := range projects {
+		for _, setting := range projectSettings {
+			if file := c.secretFiles[projectKey(projects[i].Ref, setting.key)]; file != nil {
+				*setting.field(&projects[i]) = file.current()
+			}
+		}
+	}
+	return projects
+}
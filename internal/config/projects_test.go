@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFileReadsAdditionalProjects(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "staging_service_key")
+	if err := os.WriteFile(keyFile, []byte("staging-key\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+
+	t.Setenv("SUPABASE_URL", "http://kong:8000")
+	t.Setenv("SUPABASE_ANON_KEY", "default-anon")
+	t.Setenv("SNIPPETS_MANAGEMENT_FOLDER", filepath.Join(dir, "snippets"))
+	t.Setenv("SUPABASE_STUDIO_GO_PROJECT_STAGING_SUPABASE_SERVICE_KEY_FILE", keyFile)
+
+	path := writeConfigFile(t, "studio.yaml", "supabase_studio_go:\n  projects: [staging, Bad_Ref, staging]\n  project:\n    staging:\n      name: Staging\n      supabase_url: http://staging-kong:8000\n      disk_size_gb: 32\n")
+	cfg, issues, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	var refIssues []string
+	for _, issue := range issues {
+		if issue.Key == "SUPABASE_STUDIO_GO_PROJECTS" {
+			refIssues = append(refIssues, issue.Message)
+		}
+	}
+	if len(refIssues) != 2 || !strings.Contains(refIssues[0], "Bad_Ref") || !strings.Contains(refIssues[1], "listed twice") {
+		t.Fatalf("expected issues for the invalid and the duplicate ref, got %v", refIssues)
+	}
+
+	projects := cfg.AllProjects()
+	if len(projects) != 2 || projects[0].Ref != DefaultProjectRef || projects[1].Ref != "staging" {
+		t.Fatalf("expected the default and staging projects, got %+v", projects)
+	}
+	staging, ok := cfg.Project("staging")
+	if !ok {
+		t.Fatal("expected staging to be found")
+	}
+	if staging.Name != "Staging" || staging.SupabaseURL != "http://staging-kong:8000" || staging.DiskSizeGB != 32 {
+		t.Fatalf("expected staging's own settings, got %+v", staging)
+	}
+	if staging.SupabaseServiceKey != "staging-key" {
+		t.Fatalf("expected the service key from its file, got %q", staging.SupabaseServiceKey)
+	}
+	if staging.SupabaseAnonKey != "default-anon" {
+		t.Fatalf("expected the anon key to be inherited, got %q", staging.SupabaseAnonKey)
+	}
+	if want := filepath.Join(dir, "snippets-staging"); staging.SnippetsFolder != want {
+		t.Fatalf("expected snippets folder %q, got %q", want, staging.SnippetsFolder)
+	}
+	if _, ok := cfg.Project("missing"); ok {
+		t.Fatal("expected unknown refs not to be found")
+	}
+
+	if err := os.WriteFile(keyFile, []byte("rotated-key"), 0o600); err != nil {
+		t.Fatalf("rotate secret: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(keyFile, later, later)
+	cfg.secretFiles[projectKey("staging", "SUPABASE_SERVICE_KEY")].checked = time.Time{}
+	if current, _ := cfg.Current().Project("staging"); current.SupabaseServiceKey != "rotated-key" {
+		t.Fatalf("expected rotated key, got %q", current.SupabaseServiceKey)
+	}
+}
+
+func TestHolderReloadReportsProjectChanges(t *testing.T) {
+	previous := Config{Projects: []Project{{Ref: "staging", SupabaseURL: "http://old", SupabaseServiceKey: "old-key"}}}
+	next := Config{Projects: []Project{
+		{Ref: "staging", SupabaseURL: "http://new", SupabaseServiceKey: "new-key"},
+		{Ref: "qa"},
+	}}
+
+	var keys []string
+	for _, change := range diff(&previous, &next) {
+		keys = append(keys, change.Key+"="+change.New)
+	}
+	changes := strings.Join(keys, "\n")
+	if !strings.Contains(changes, "Projects.staging.SUPABASE_URL") || !strings.Contains(changes, "Projects.qa") {
+		t.Fatalf("expected the changed setting and the added project, got %q", changes)
+	}
+	if strings.Contains(changes, "new-key") || strings.Contains(changes, "SUPABASE_SERVICE_KEY") {
+		t.Fatalf("expected secrets to stay out of the change list, got %q", changes)
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretRecheckInterval bounds how often a secret file is stat'ed for changes.
+const secretRecheckInterval = time.Second
+
+// secretFields lists every secret that may be read from a file named by <KEY>_FILE, in the
+// Docker/Kubernetes secrets convention. Keys are tried in order, like their plain variants.
+var secretFields = []struct {
+	keys  []string
+	field func(*Config) *string
+}{
+	{[]string{"SUPABASE_SERVICE_KEY", "SUPABASE_SERVICE_ROLE_KEY", "SERVICE_ROLE_KEY", "SERVICE_KEY"}, func(c *Config) *string { return &c.SupabaseServiceKey }},
+	{[]string{"SUPABASE_ANON_KEY"}, func(c *Config) *string { return &c.SupabaseAnonKey }},
+	{[]string{"PG_META_CRYPTO_KEY"}, func(c *Config) *string { return &c.PgMetaCryptoKey }},
+	{[]string{"POSTGRES_PASSWORD"}, func(c *Config) *string { return &c.PostgresPassword }},
+	{[]string{"LOGFLARE_PRIVATE_ACCESS_TOKEN"}, func(c *Config) *string { return &c.LogflareToken }},
+	{[]string{"SUPPORT_SUPABASE_SECRET_KEY"}, func(c *Config) *string { return &c.SupportAPIKey }},
+	{[]string{"OPENAI_API_KEY"}, func(c *Config) *string { return &c.OpenAIAPIKey }},
+	{[]string{"STATUSPAGE_API_KEY"}, func(c *Config) *string { return &c.StatusPageAPIKey }},
+	{[]string{"AUTH_JWT_SECRET"}, func(c *Config) *string { return &c.AuthJWTSecret }},
+	{[]string{"SUPABASE_STUDIO_GO_SESSION_SECRET"}, func(c *Config) *string { return &c.StudioSessionSecret }},
+	{[]string{"SUPABASE_STUDIO_GO_OIDC_CLIENT_SECRET"}, func(c *Config) *string { return &c.StudioOIDCClientSecret }},
+	{[]string{"SUPABASE_STUDIO_GO_METRICS_TOKEN"}, func(c *Config) *string { return &c.MetricsToken }},
+	{[]string{"SUPABASE_STUDIO_GO_STATE_DATABASE_URL"}, func(c *Config) *string { return &c.StateDatabaseURL }},
+}
+
+// secretFile holds the last good contents of a secret file. A file that disappears or
+// becomes unreadable keeps serving the previous value.
+type secretFile struct {
+	path string
+
+	mu      sync.Mutex
+	value   string
+	modTime time.Time
+	checked time.Time
+}
+
+func openSecretFile(path string) (*secretFile, error) {
+	file := &secretFile{path: path}
+	if err := file.reload(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f *secretFile) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	f.value = strings.TrimRight(string(data), "\r\n")
+	f.modTime = info.ModTime()
+	return nil
+}
+
+func (f *secretFile) current() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.checked) >= secretRecheckInterval {
+		f.checked = time.Now()
+		if info, err := os.Stat(f.path); err == nil && !info.ModTime().Equal(f.modTime) {
+			_ = f.reload()
+		}
+	}
+	return f.value
+}
+
+// loadSecrets overrides secrets with the contents of their *_FILE variants.
+func (s *source) loadSecrets(cfg *Config) {
+	cfg.secretFiles = map[string]*secretFile{}
+	for _, secret := range secretFields {
+		for _, key := range secret.keys {
+			path := strings.TrimSpace(s.env(key + "_FILE"))
+			if path == "" {
+				if s.env(key) != "" {
+					break
+				}
+				continue
+			}
+			if s.env(key) != "" {
+				s.issues = append(s.issues, Issue{Key: key, Message: fmt.Sprintf("both %s and %s_FILE are set, using the file", key, key)})
+			}
+
+			file, err := openSecretFile(path)
+			if err != nil {
+				s.issues = append(s.issues, Issue{Key: key + "_FILE", Message: fmt.Sprintf("cannot read secret file: %v", err)})
+				break
+			}
+			*secret.field(cfg) = file.value
+			cfg.secretFiles[secret.keys[0]] = file
+			break
+		}
+	}
+}
+
+// Current returns a copy of c whose file-based secrets reflect the files on disk now, so
+// rotated secrets take effect without a restart.
+func (c Config) Current() Config {
+	for _, secret := range secretFields {
+		if file := c.secretFiles[secret.keys[0]]; file != nil {
+			*secret.field(&c) = file.current()
+		}
+	}
+	c.Projects = c.currentProjects()
+	return c
+}
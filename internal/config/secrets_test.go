@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSecretFilesOverrideAndReload(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "service_key")
+	if err := os.WriteFile(keyFile, []byte("first-key\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+
+	t.Setenv("SUPABASE_SERVICE_KEY", "")
+	t.Setenv("SUPABASE_SERVICE_ROLE_KEY", "plain-role-key")
+	t.Setenv("SUPABASE_SERVICE_KEY_FILE", keyFile)
+	t.Setenv("POSTGRES_PASSWORD_FILE", filepath.Join(dir, "missing"))
+
+	cfg, issues, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if cfg.SupabaseServiceKey != "first-key" {
+		t.Fatalf("expected key from file without trailing newline, got %q", cfg.SupabaseServiceKey)
+	}
+	found := false
+	for _, issue := range issues {
+		if issue.Key == "POSTGRES_PASSWORD_FILE" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected an issue for the unreadable secret file, got %v", issues)
+	}
+
+	if err := os.WriteFile(keyFile, []byte("rotated-key"), 0o600); err != nil {
+		t.Fatalf("rotate secret: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(keyFile, later, later)
+	cfg.secretFiles["SUPABASE_SERVICE_KEY"].checked = time.Time{}
+
+	if got := cfg.Current().SupabaseServiceKey; got != "rotated-key" {
+		t.Fatalf("expected rotated key, got %q", got)
+	}
+
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatalf("remove secret: %v", err)
+	}
+	cfg.secretFiles["SUPABASE_SERVICE_KEY"].checked = time.Time{}
+	if got := cfg.Current().SupabaseServiceKey; got != "rotated-key" {
+		t.Fatalf("expected last good key to stay in use, got %q", got)
+	}
+}
+
+func TestSecretFileAndPlainValueBothSet(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "jwt")
+	if err := os.WriteFile(secret, []byte("from-file"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	t.Setenv("AUTH_JWT_SECRET", "from-env")
+	t.Setenv("AUTH_JWT_SECRET_FILE", secret)
+
+	cfg, issues, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if cfg.AuthJWTSecret != "from-file" {
+		t.Fatalf("expected the file to win, got %q", cfg.AuthJWTSecret)
+	}
+	for _, issue := range issues {
+		if issue.Key == "AUTH_JWT_SECRET" {
+			return
+		}
+	}
+	t.Fatalf("expected an issue about both variants being set, got %v", issues)
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	samplePgMetaCryptoKey  = "SAMPLE_KEY"
+	sampleAuthJWTSecret    = "super-secret-jwt-token-with-at-least-32-characters-long"
+	samplePostgresPassword = "postgres"
+)
+
+// Issue is a problem found while loading the configuration. Insecure issues stop the
+// server from starting in production mode.
+type Issue struct {
+	Key      string
+	Message  string
+	Insecure bool
+}
+
+func (i Issue) String() string {
+	return i.Key + ": " + i.Message
+}
+
+var sensitiveEnvFragments = []string{"SECRET", "SERVICE_ROLE", "SERVICE_KEY", "TOKEN", "PASSWORD", "PRIVATE"}
+
+// SensitiveEnvKey reports whether a variable name suggests a secret, which must never be
+// sent to the browser even when listed in PublicEnvKeys.
+func SensitiveEnvKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, fragment := range sensitiveEnvFragments {
+		if strings.Contains(upper, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Insecure returns the issues that production mode refuses to start with.
+func Insecure(issues []Issue) []Issue {
+	var insecure []Issue
+	for _, issue := range issues {
+		if issue.Insecure {
+			insecure = append(insecure, issue)
+		}
+	}
+	return insecure
+}
+
+func (s *source) validate(cfg Config) []Issue {
+	issues := append([]Issue(nil), s.issues...)
+
+	for key := range s.file {
+		if !s.known[key] {
+			issues = append(issues, Issue{Key: key, Message: "unknown setting"})
+		}
+	}
+
+	urls := map[string]string{
+		"SUPABASE_URL":                         cfg.SupabaseURL,
+		"SUPABASE_PUBLIC_URL":                  cfg.SupabasePublicURL,
+		"STUDIO_PG_META_URL":                   cfg.StudioPgMetaURL,
+		"LOGFLARE_URL":                         cfg.LogflareURL,
+		"NEXT_PUBLIC_SUPPORT_API_URL":          cfg.SupportAPIURL,
+		"OPENAI_API_URL":                       cfg.OpenAIAPIURL,
+		"SUPABASE_STUDIO_GO_OIDC_ISSUER":       cfg.StudioOIDCIssuer,
+		"SUPABASE_STUDIO_GO_OIDC_REDIRECT_URL": cfg.StudioOIDCRedirectURL,
+		"SUPABASE_STUDIO_GO_OTLP_ENDPOINT":     cfg.TracingEndpoint,
+	}
+	for _, project := range cfg.Projects {
+		urls[projectKey(project.Ref, "SUPABASE_URL")] = project.SupabaseURL
+		urls[projectKey(project.Ref, "SUPABASE_PUBLIC_URL")] = project.SupabasePublicURL
+		urls[projectKey(project.Ref, "STUDIO_PG_META_URL")] = project.StudioPgMetaURL
+		urls[projectKey(project.Ref, "LOGFLARE_URL")] = project.LogflareURL
+	}
+	for key, value := range urls {
+		if value == "" {
+			continue
+		}
+		if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			issues = append(issues, Issue{Key: key, Message: fmt.Sprintf("malformed URL %q, expected http(s)://host[:port][/path]", value)})
+		}
+	}
+
+	for key, origins := range map[string][]string{
+		"SUPABASE_STUDIO_GO_CORS_ALLOWED_ORIGINS": cfg.CORSAllowedOrigins,
+		"SUPABASE_STUDIO_GO_CSRF_TRUSTED_ORIGINS": cfg.CSRFTrustedOrigins,
+	} {
+		for _, origin := range origins {
+			if origin == "*" && key == "SUPABASE_STUDIO_GO_CORS_ALLOWED_ORIGINS" {
+				continue
+			}
+			if parsed, err := url.Parse(origin); err != nil || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" {
+				issues = append(issues, Issue{Key: key, Message: fmt.Sprintf("malformed origin %q, expected scheme://host[:port]", origin)})
+			}
+		}
+	}
+	if cfg.CORSAllowCredentials && slices.Contains(cfg.CORSAllowedOrigins, "*") {
+		issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_CORS_ALLOW_CREDENTIALS", Message: `credentials cannot be allowed for the "*" origin, list the origins instead`, Insecure: true})
+	}
+
+	switch strings.ToLower(cfg.CSPMode) {
+	case "enforce", "report-only":
+	case "off":
+		issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_CSP_MODE", Message: "no Content-Security-Policy is sent, set one at the proxy"})
+	default:
+		issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_CSP_MODE", Message: fmt.Sprintf("unknown mode %q, expected enforce, report-only or off", cfg.CSPMode)})
+	}
+	if slices.Contains(cfg.CSPFrameAncestors, "*") {
+		issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_CSP_FRAME_ANCESTORS", Message: "any site may embed the dashboard", Insecure: true})
+	}
+
+	switch strings.ToLower(cfg.StateBackend) {
+	case "file", "postgres":
+	default:
+		issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_STATE_BACKEND", Message: fmt.Sprintf("unknown backend %q, expected file or postgres", cfg.StateBackend)})
+	}
+	if cfg.StateKeyFile == "" && len(cfg.StatePreviousKeyFiles) > 0 {
+		issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_STATE_PREVIOUS_KEY_FILES", Message: "previous state keys need SUPABASE_STUDIO_GO_STATE_KEY_FILE"})
+	}
+	for _, path := range append([]string{cfg.StateKeyFile}, cfg.StatePreviousKeyFiles...) {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_STATE_KEY_FILE", Message: fmt.Sprintf("cannot read state key %q, dashboard settings cannot be saved", path)})
+		}
+	}
+
+	if cfg.FrontendDir != "" {
+		if info, err := os.Stat(cfg.FrontendDir); err != nil || !info.IsDir() {
+			issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_FRONTEND_DIR", Message: fmt.Sprintf("%q is not a directory, serving the embedded frontend", cfg.FrontendDir)})
+		}
+	}
+
+	for _, key := range cfg.PublicEnvKeys {
+		switch {
+		case !strings.HasPrefix(key, "NEXT_PUBLIC_"):
+			issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_PUBLIC_ENV_KEYS", Message: fmt.Sprintf("%s is not exposed, only NEXT_PUBLIC_* variables can be", key)})
+		case SensitiveEnvKey(key):
+			issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_PUBLIC_ENV_KEYS", Message: fmt.Sprintf("%s is not exposed, it looks like a secret", key)})
+		}
+	}
+
+	if mode, err := strconv.ParseUint(cfg.ListenSocketMode, 8, 32); err != nil || mode > 0o777 {
+		issues = append(issues, Issue{Key: "SUPABASE_STUDIO_GO_LISTEN_SOCKET_MODE", Message: fmt.Sprintf("%q is not octal permissions such as 0660", cfg.ListenSocketMode)})
+	}
+
+	if cfg.PgMetaCryptoKey == samplePgMetaCryptoKey {
+		issues = append(issues, Issue{Key: "PG_META_CRYPTO_KEY", Message: "uses the public sample key", Insecure: true})
+	}
+	if cfg.AuthJWTSecret == sampleAuthJWTSecret {
+		issues = append(issues, Issue{Key: "AUTH_JWT_SECRET", Message: "uses the public sample secret", Insecure: true})
+	}
+	if cfg.PostgresPassword == samplePostgresPassword {
+		issues = append(issues, Issue{Key: "POSTGRES_PASSWORD", Message: `uses the default password "postgres"`, Insecure: true})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Key < issues[j].Key })
+	return issues
+}
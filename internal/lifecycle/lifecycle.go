@@ -0,0 +1,76 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+// Drain tracks a graceful shutdown in two phases. Draining starts as soon as a stop signal
+// arrives and makes readiness checks fail; stopping asks long-lived handlers such as SSE
+// streams to wrap up before the shutdown deadline.
+type Drain struct {
+	startOnce sync.Once
+	draining  chan struct{}
+
+	stopCtx context.Context
+	stop    context.CancelFunc
+}
+
+func NewDrain() *Drain {
+	stopCtx, stop := context.WithCancel(context.Background())
+	return &Drain{
+		draining: make(chan struct{}),
+		stopCtx:  stopCtx,
+		stop:     stop,
+	}
+}
+
+// Start marks the process as draining. It is safe to call more than once.
+func (d *Drain) Start() {
+	d.startOnce.Do(func() { close(d.draining) })
+}
+
+// Stop asks in-flight long-lived handlers to finish now. It implies Start.
+func (d *Drain) Stop() {
+	d.Start()
+	d.stop()
+}
+
+func (d *Drain) Draining() bool {
+	if d == nil {
+		return false
+	}
+	select {
+	case <-d.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+type contextKey struct{}
+
+// WithDrain attaches d to ctx, typically as the http.Server base context.
+func WithDrain(ctx context.Context, d *Drain) context.Context {
+	return context.WithValue(ctx, contextKey{}, d)
+}
+
+func FromContext(ctx context.Context) *Drain {
+	d, _ := ctx.Value(contextKey{}).(*Drain)
+	return d
+}
+
+// IsDraining reports whether the server handling ctx is shutting down.
+func IsDraining(ctx context.Context) bool {
+	return FromContext(ctx).Draining()
+}
+
+// AfterStop arranges for f to run in its own goroutine once the server handling ctx asks
+// handlers to stop. Like context.AfterFunc, the returned function cancels that.
+func AfterStop(ctx context.Context, f func()) func() bool {
+	d := FromContext(ctx)
+	if d == nil {
+		return func() bool { return false }
+	}
+	return context.AfterFunc(d.stopCtx, f)
+}
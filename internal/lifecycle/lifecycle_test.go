@@ -0,0 +1,46 @@
+package lifecycle
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDrainPhases(t *testing.T) {
+	drain := NewDrain()
+	ctx := WithDrain(context.Background(), drain)
+
+	stopped := make(chan struct{})
+	AfterStop(ctx, func() { close(stopped) })
+
+	if IsDraining(ctx) {
+		t.Fatal("expected not draining before Start")
+	}
+	drain.Start()
+	drain.Start()
+	if !IsDraining(ctx) {
+		t.Fatal("expected draining after Start")
+	}
+	select {
+	case <-stopped:
+		t.Fatal("stop callback ran before Stop")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	drain.Stop()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop callback did not run")
+	}
+}
+
+func TestWithoutDrain(t *testing.T) {
+	ctx := context.Background()
+	if IsDraining(ctx) {
+		t.Fatal("expected no drain in a plain context")
+	}
+	if AfterStop(ctx, func() {})() {
+		t.Fatal("expected AfterStop without a drain to be a no-op")
+	}
+}
@@ -0,0 +1,89 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/activation"
+)
+
+const unixPrefix = "unix:"
+
+// IsUnix reports whether addr names a Unix socket, as in unix:/run/studio.sock.
+func IsUnix(addr string) bool {
+	return strings.HasPrefix(addr, unixPrefix)
+}
+
+// Listen opens addr, which is either a TCP host:port or unix:/path/to.sock. A Unix socket
+// gets the given permissions, and a stale socket left behind by a crashed process is
+// replaced; any other file at that path is an error.
+func Listen(addr string, mode fs.FileMode) (net.Listener, error) {
+	if !IsUnix(addr) {
+		return net.Listen("tcp", addr)
+	}
+
+	path := strings.TrimPrefix(addr, unixPrefix)
+	if path == "" {
+		return nil, errors.New("unix socket address needs a path, e.g. unix:/run/supabase-studio-go.sock")
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// ParseMode parses octal socket permissions such as 0660; empty means 0660.
+func ParseMode(value string) (fs.FileMode, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0o660, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid socket mode %q, expected octal permissions such as 0660", value)
+	}
+	return fs.FileMode(mode), nil
+}
+
+// Activated returns the sockets handed over by systemd socket activation (LISTEN_FDS), in
+// the order of the socket unit's ListenStream= lines, or none when started normally.
+func Activated() ([]net.Listener, error) {
+	listeners, err := activation.Listeners()
+	if err != nil {
+		return nil, fmt.Errorf("failed to use systemd sockets: %w", err)
+	}
+	for i, ln := range listeners {
+		if ln == nil {
+			for _, ln := range listeners {
+				if ln != nil {
+					_ = ln.Close()
+				}
+			}
+			return nil, fmt.Errorf("systemd socket %d is not a stream socket", i)
+		}
+	}
+	return listeners, nil
+}
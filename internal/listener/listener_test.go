@@ -0,0 +1,65 @@
+package listener
+
+import (
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnixSocketSetsModeAndReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "studio.sock")
+
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	ln, err := Listen("unix:"+path, 0o600)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat socket: %v", err)
+	}
+	if info.Mode().Type() != fs.ModeSocket || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected socket with mode 0600, got %v", info.Mode())
+	}
+
+	if _, err := Listen("unix:"+path, 0o600); err == nil {
+		t.Fatal("expected a socket in use to be refused")
+	}
+}
+
+func TestListenUnixRefusesToReplaceRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "studio.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, err := Listen("unix:"+path, 0o660); err == nil {
+		t.Fatal("expected an error for a regular file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "data" {
+		t.Fatal("expected the file to be left alone")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for value, want := range map[string]fs.FileMode{"": 0o660, "0660": 0o660, "600": 0o600, "0777": 0o777} {
+		if got, err := ParseMode(value); err != nil || got != want {
+			t.Fatalf("ParseMode(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"rw-rw----", "0999", "01777"} {
+		if _, err := ParseMode(value); err == nil {
+			t.Fatalf("expected ParseMode(%q) to fail", value)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "supabase_studio"
+
+// registry is private so tests and multiple routers in one process never collide on
+// registration with the global default registry.
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by chi route pattern.",
+	}, []string{"method", "route", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by chi route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	upstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Requests sent to upstream services, by response status.",
+	}, []string{"upstream", "method", "code"})
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Time until upstream response headers were received.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"upstream", "method"})
+	upstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Upstream requests that failed without a response.",
+	}, []string{"upstream", "method"})
+
+	streamsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sse_streams_in_flight",
+		Help:      "Server-sent event streams currently open.",
+	}, []string{"route"})
+	streamsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sse_streams_total",
+		Help:      "Server-sent event streams opened.",
+	}, []string{"route"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		upstreamRequests,
+		upstreamDuration,
+		upstreamErrors,
+		streamsInFlight,
+		streamsTotal,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records request counts and latency labelled by the matched chi route pattern,
+// so that path parameters do not blow up label cardinality.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route := routePattern(r)
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// TrackStream counts an open SSE stream until the returned function is called.
+func TrackStream(r *http.Request) func() {
+	route := routePattern(r)
+	streamsTotal.WithLabelValues(route).Inc()
+	gauge := streamsInFlight.WithLabelValues(route)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "unmatched"
+}
+
+// Transport instruments an http.RoundTripper. Classify names the upstream a request is for.
+type Transport struct {
+	Base     http.RoundTripper
+	Classify func(*http.Request) string
+}
+
+func NewTransport(base http.RoundTripper, classify func(*http.Request) string) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base, Classify: classify}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	upstream := "other"
+	if t.Classify != nil {
+		upstream = t.Classify(req)
+	}
+
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	upstreamDuration.WithLabelValues(upstream, req.Method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		upstreamErrors.WithLabelValues(upstream, req.Method).Inc()
+		return nil, err
+	}
+	upstreamRequests.WithLabelValues(upstream, req.Method, strconv.Itoa(resp.StatusCode)).Inc()
+	return resp, nil
+}
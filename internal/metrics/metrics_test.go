@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestMiddlewareLabelsRequestsByRoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/projects/{ref}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	for _, ref := range []string{"alpha", "beta"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/projects/"+ref, nil))
+	}
+
+	body := scrape(t)
+	if !strings.Contains(body, `supabase_studio_http_requests_total{code="418",method="GET",route="/projects/{ref}"} 2`) {
+		t.Fatalf("expected requests to be counted per route pattern, got:\n%s", body)
+	}
+	if strings.Contains(body, "/projects/alpha") {
+		t.Fatalf("expected raw paths not to be used as labels")
+	}
+}
+
+func TestTransportRecordsUpstreamStatusAndErrors(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, func(*http.Request) string { return "pg-meta-test" })}
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if _, err := client.Get("http://127.0.0.1:0/unreachable"); err == nil {
+		t.Fatalf("expected request to an invalid port to fail")
+	}
+
+	body := scrape(t)
+	if !strings.Contains(body, `supabase_studio_upstream_requests_total{code="502",method="GET",upstream="pg-meta-test"} 1`) {
+		t.Fatalf("expected upstream status to be counted, got:\n%s", body)
+	}
+	if !strings.Contains(body, `supabase_studio_upstream_errors_total{method="GET",upstream="pg-meta-test"} 1`) {
+		t.Fatalf("expected upstream error to be counted, got:\n%s", body)
+	}
+	if !strings.Contains(body, `supabase_studio_upstream_request_duration_seconds_count{method="GET",upstream="pg-meta-test"} 2`) {
+		t.Fatalf("expected upstream latency to be observed, got:\n%s", body)
+	}
+}
+
+func TestTrackStreamCountsOpenStreams(t *testing.T) {
+	router := chi.NewRouter()
+	release := make(chan struct{})
+	opened := make(chan struct{})
+	router.Post("/ai/docs", func(w http.ResponseWriter, r *http.Request) {
+		defer TrackStream(r)()
+		close(opened)
+		<-release
+	})
+
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/ai/docs", nil))
+		close(done)
+	}()
+
+	<-opened
+	if body := scrape(t); !strings.Contains(body, `supabase_studio_sse_streams_in_flight{route="/ai/docs"} 1`) {
+		t.Fatalf("expected one stream in flight, got:\n%s", body)
+	}
+	close(release)
+	<-done
+	if body := scrape(t); !strings.Contains(body, `supabase_studio_sse_streams_in_flight{route="/ai/docs"} 0`) {
+		t.Fatalf("expected stream to be released, got:\n%s", body)
+	}
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Budget caps the AI tokens each client may use per UTC day.
+type Budget struct {
+	limit int64
+
+	mu    sync.Mutex
+	day   string
+	spent map[string]int64
+}
+
+// NewBudget allows limit tokens per client and day. It returns nil, an unlimited budget,
+// when limit is not positive.
+func NewBudget(limit int64) *Budget {
+	if limit <= 0 {
+		return nil
+	}
+	return &Budget{limit: limit, spent: map[string]int64{}}
+}
+
+// Exhausted reports whether key has used up today's budget, and if so how long until it
+// resets at midnight UTC.
+func (b *Budget) Exhausted(key string) (bool, time.Duration) {
+	if b == nil {
+		return false, 0
+	}
+
+	now := time.Now().UTC()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(now)
+	if b.spent[key] < b.limit {
+		return false, 0
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return true, midnight.Sub(now)
+}
+
+// Spend records tokens used by key. The request that crosses the limit still completes;
+// the next one is refused.
+func (b *Budget) Spend(key string, tokens int64) {
+	if b == nil || key == "" || tokens <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover(time.Now().UTC())
+	b.spent[key] += tokens
+}
+
+func (b *Budget) rollover(now time.Time) {
+	if day := now.Format(time.DateOnly); day != b.day {
+		b.day = day
+		clear(b.spent)
+	}
+}
+
+// Middleware refuses requests with 429 once the client's budget for the day is spent.
+// Handlers report usage with Spend and KeyFromContext.
+func (b *Budget) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, key := withKey(r)
+		if exhausted, retryAfter := b.Exhausted(key); exhausted {
+			tooManyRequests(w, retryAfter, "Daily AI token budget exhausted")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"golang.org/x/time/rate"
+)
+
+// Limiter is a set of token buckets, one per client key. Each bucket holds a minute's worth
+// of requests and refills continuously.
+type Limiter struct {
+	limit rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// New allows perMinute requests per client and minute. It returns nil, which allows
+// everything, when perMinute is not positive.
+func New(perMinute int) *Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &Limiter{
+		limit:   rate.Limit(float64(perMinute) / 60),
+		burst:   perMinute,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it reports how long
+// until the next token.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	l.sweep(now)
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.seen = now
+	l.mu.Unlock()
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep forgets buckets idle long enough to have refilled completely, since a fresh bucket
+// behaves the same.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) >= time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware answers 429 with Retry-After once the client's bucket is empty. A nil
+// Limiter lets every request through.
+func Middleware(l *Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, key := withKey(r)
+			if ok, retryAfter := l.Allow(key); !ok {
+				tooManyRequests(w, retryAfter, "Too many requests, please slow down")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type keyContextKey struct{}
+
+// KeyFromContext returns the client key set by Middleware or Budget.Middleware.
+func KeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(keyContextKey{}).(string)
+	return key
+}
+
+// withKey identifies the client by signed-in user, or by address for anonymous requests.
+// The address is the one set by middleware.RealIP.
+func withKey(r *http.Request) (*http.Request, string) {
+	if key := KeyFromContext(r.Context()); key != "" {
+		return r, key
+	}
+
+	var key string
+	if identity, ok := auth.IdentityFromContext(r.Context()); ok && identity.Username != "" {
+		key = "user:" + identity.Username
+	} else {
+		host := r.RemoteAddr
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		key = "ip:" + host
+	}
+	return r.WithContext(context.WithValue(r.Context(), keyContextKey{}, key)), key
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message},
+	})
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+)
+
+func TestMiddlewareLimitsEachClientSeparately(t *testing.T) {
+	handler := Middleware(New(2))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := func(remoteAddr, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ai/sql/title-v2", nil)
+		req.RemoteAddr = remoteAddr
+		if username != "" {
+			req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{Username: username}))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := request("10.0.0.1:1234", ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d: expected 204, got %d", i, rec.Code)
+		}
+	}
+	rec := request("10.0.0.1:5678", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the bucket is empty, got %d", rec.Code)
+	}
+	if seconds, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil || seconds < 1 || seconds > 30 {
+		t.Fatalf("expected Retry-After of up to 30s, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	if rec := request("10.0.0.2:1234", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected another address to have its own bucket, got %d", rec.Code)
+	}
+	if rec := request("10.0.0.1:1234", "alice"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected a signed-in user to be keyed by username, got %d", rec.Code)
+	}
+}
+
+func TestNilLimiterAndBudgetAllowEverything(t *testing.T) {
+	if ok, _ := New(0).Allow("ip:10.0.0.1"); !ok {
+		t.Fatal("expected a disabled limiter to allow requests")
+	}
+	budget := NewBudget(0)
+	budget.Spend("ip:10.0.0.1", 1_000_000)
+	if exhausted, _ := budget.Exhausted("ip:10.0.0.1"); exhausted {
+		t.Fatal("expected a disabled budget to be unlimited")
+	}
+}
+
+func TestBudgetRefusesClientsOverDailyTokens(t *testing.T) {
+	budget := NewBudget(100)
+	var seenKey string
+	handler := budget.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenKey = KeyFromContext(r.Context())
+		budget.Spend(seenKey, 60)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/ai/docs", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+		if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Fatal("expected Retry-After until the budget resets")
+		}
+	}
+
+	if seenKey != "ip:10.0.0.1" {
+		t.Fatalf("expected the client key in the request context, got %q", seenKey)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("expected the request after crossing the budget to be refused, got %v", codes)
+	}
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// assetEncodings lists the precomputed content codings in order of preference.
+var assetEncodings = []string{"br", "zstd", "gzip"}
+
+var assetSuffixes = map[string]string{"br": ".br", "zstd": ".zst", "gzip": ".gz"}
+
+// staticAsset is an embedded file with its compressed variants. It is read on its first
+// request, and the variants the build did not ship are compressed in the background, so
+// the identity is served until they are ready. Variants that do not save at least a tenth
+// of the size are dropped.
+type staticAsset struct {
+	store *assetStore
+	name  string
+
+	prepareOnce  sync.Once
+	err          error
+	contentType  string
+	etag         string
+	compressible bool
+
+	variants atomic.Pointer[map[string][]byte]
+	// compressed is closed once variants holds every variant worth keeping.
+	compressed chan struct{}
+}
+
+// assetStore serves the frontend's static files, compressing each once instead of on every
+// request. HTML pages are not stored, since they carry a nonce.
+type assetStore struct {
+	static fs.FS
+	assets map[string]*staticAsset
+	// slots bounds the assets compressed at the same time.
+	slots       chan struct{}
+	zstdEncoder func() *zstd.Encoder
+}
+
+func newAssetStore(static fs.FS) *assetStore {
+	store := &assetStore{
+		static: static,
+		assets: map[string]*staticAsset{},
+		slots:  make(chan struct{}, runtime.GOMAXPROCS(0)),
+		zstdEncoder: sync.OnceValue(func() *zstd.Encoder {
+			encoder, _ := zstd.NewWriter(nil,
+				zstd.WithEncoderLevel(zstd.SpeedBestCompression),
+				zstd.WithWindowSize(8<<20), // browsers refuse larger windows
+				zstd.WithSingleSegment(false),
+			)
+			return encoder
+		}),
+	}
+	_ = fs.WalkDir(static, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || strings.HasSuffix(name, ".html") || isCompressedSibling(static, name) {
+			return nil
+		}
+		store.assets[name] = &staticAsset{store: store, name: name, compressed: make(chan struct{})}
+		return nil
+	})
+	return store
+}
+
+// isCompressedSibling reports whether name is a variant produced at build time, such as
+// app.js.br next to app.js.
+func isCompressedSibling(static fs.FS, name string) bool {
+	for _, suffix := range assetSuffixes {
+		if original, ok := strings.CutSuffix(name, suffix); ok && fileExists(static, original) {
+			return true
+		}
+	}
+	return false
+}
+
+// prepare hashes the asset and picks up build-time variants, then starts compressing the
+// rest. Only the variants stay in memory; the identity is read from the file system.
+func (a *staticAsset) prepare() error {
+	a.prepareOnce.Do(func() {
+		static := a.store.static
+		data, err := fs.ReadFile(static, a.name)
+		if err != nil {
+			a.err = err
+			close(a.compressed)
+			return
+		}
+		sum := sha256.Sum256(data)
+		a.etag = base64.RawURLEncoding.EncodeToString(sum[:12])
+		a.contentType = mime.TypeByExtension(path.Ext(a.name))
+		if a.contentType == "" {
+			a.contentType = http.DetectContentType(data)
+		}
+		if strings.HasSuffix(a.name, ".ts") {
+			a.contentType = "text/typescript"
+		}
+		a.compressible = compressible(a.contentType)
+
+		variants := map[string][]byte{}
+		var missing []string
+		if a.compressible {
+			for _, encoding := range assetEncodings {
+				if variant, err := fs.ReadFile(static, a.name+assetSuffixes[encoding]); err == nil {
+					variants[encoding] = variant
+				} else {
+					missing = append(missing, encoding)
+				}
+			}
+		}
+		a.variants.Store(&variants)
+		if len(missing) == 0 {
+			close(a.compressed)
+			return
+		}
+		go a.compress(data, variants, missing)
+	})
+	return a.err
+}
+
+func (a *staticAsset) compress(data []byte, built map[string][]byte, missing []string) {
+	defer close(a.compressed)
+	a.store.slots <- struct{}{}
+	defer func() { <-a.store.slots }()
+
+	started := time.Now()
+	variants := maps.Clone(built)
+	for _, encoding := range missing {
+		variant, err := compressAsset(encoding, data, a.store.zstdEncoder())
+		if err != nil {
+			slog.Warn("failed to compress static asset", "path", a.name, "encoding", encoding, "error", err)
+			continue
+		}
+		if len(variant) < len(data)*9/10 {
+			variants[encoding] = variant
+		}
+	}
+	a.variants.Store(&variants)
+	slog.Debug("static asset compressed", "path", a.name, "variants", len(variants), "duration", time.Since(started))
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+xml") || strings.HasSuffix(mediaType, "+json") {
+		return true
+	}
+	switch mediaType {
+	case "application/javascript", "application/json", "application/manifest+json", "application/wasm",
+		"application/xml", "font/ttf", "font/otf", "image/svg+xml", "image/x-icon", "image/vnd.microsoft.icon":
+		return true
+	}
+	return false
+}
+
+func compressAsset(encoding string, data []byte, zstdEncoder *zstd.Encoder) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "zstd":
+		return zstdEncoder.EncodeAll(data, nil), nil
+	case "br":
+		writer = brotli.NewWriterLevel(&buf, 9)
+	default:
+		gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		writer = gz
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *assetStore) lookup(name string) *staticAsset {
+	if s == nil {
+		return nil
+	}
+	return s.assets[name]
+}
+
+// serve writes the variant the client prefers. The ETag is the content hash, suffixed per
+// encoding, and If-None-Match and Range are answered by http.ServeContent.
+func (a *staticAsset) serve(w http.ResponseWriter, r *http.Request, name string) {
+	if err := a.prepare(); err != nil {
+		slog.WarnContext(r.Context(), "failed to load static asset", "path", a.name, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	variants := *a.variants.Load()
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), variants)
+	etag := a.etag
+	if a.compressible {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	w.Header().Set("Content-Type", a.contentType)
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+		w.Header().Set("ETag", strconv.Quote(etag+"-"+encoding))
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(variants[encoding]))
+		return
+	}
+
+	w.Header().Set("ETag", strconv.Quote(etag))
+	file, err := a.store.static.Open(a.name)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	http.ServeContent(w, r, name, time.Time{}, content)
+}
+
+// negotiateEncoding picks the most preferred available coding the client accepts, or ""
+// for the identity.
+func negotiateEncoding(header string, variants map[string][]byte) string {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if coding != "" {
+			accepted[strings.ToLower(coding)] = quality
+		}
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range assetEncodings {
+		if _, ok := variants[encoding]; !ok {
+			continue
+		}
+		quality, ok := accepted[encoding]
+		if !ok {
+			quality, ok = accepted["*"]
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/andybalholm/brotli"
+)
+
+func TestSPAHandlerServesPrecompressedAssets(t *testing.T) {
+	script := []byte(strings.Repeat("console.log('supabase studio');\n", 200))
+	static := fstest.MapFS{
+		"_next/static/chunks/app.js": {Data: script},
+		"img/logo.png":               {Data: []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("x", 2048))},
+	}
+	assets := newAssetStore(static)
+	handler := gzipMiddleware(assets, "/studio")(spaHandler(t.Context(), static, false, assets, config.NewHolder(config.Config{BasePath: "/studio"}, "")))
+	waitCompressed(t, assets, "_next/static/chunks/app.js")
+
+	req := httptest.NewRequest(http.MethodGet, "/studio/_next/static/chunks/app.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "br" {
+		t.Fatalf("expected a brotli response, got %d %v", rec.Code, rec.Header())
+	}
+	body, err := io.ReadAll(brotli.NewReader(rec.Body))
+	if err != nil || !bytes.Equal(body, script) {
+		t.Fatalf("brotli variant does not decode to the asset: %v", err)
+	}
+	etag := rec.Header().Get("ETag")
+	if !strings.HasSuffix(etag, `-br"`) || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("unexpected caching headers: %v", rec.Header())
+	}
+	if rec.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Fatalf("unexpected Cache-Control %q", rec.Header().Get("Cache-Control"))
+	}
+
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("expected 304 for a matching ETag, got %d", rec.Code)
+	}
+
+	plain := httptest.NewRequest(http.MethodGet, "/studio/_next/static/chunks/app.js", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, plain)
+	if rec.Header().Get("Content-Encoding") != "" || !bytes.Equal(rec.Body.Bytes(), script) {
+		t.Fatalf("expected the identity for clients without Accept-Encoding, got %v", rec.Header())
+	}
+
+	image := httptest.NewRequest(http.MethodGet, "/studio/img/logo.png", nil)
+	image.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, image)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected images to be served as they are, got %v", rec.Header())
+	}
+}
+
+func TestAssetStorePrefersBuildTimeVariants(t *testing.T) {
+	prebuilt := []byte("prebuilt gzip bytes")
+	static := fstest.MapFS{
+		"app.css":    {Data: []byte(strings.Repeat("body { color: #ededed; }\n", 100))},
+		"app.css.gz": {Data: prebuilt},
+	}
+	assets := newAssetStore(static)
+
+	if assets.lookup("app.css.gz") != nil {
+		t.Fatal("expected build-time variants not to be served as assets")
+	}
+	if asset := waitCompressed(t, assets, "app.css"); !bytes.Equal((*asset.variants.Load())["gzip"], prebuilt) {
+		t.Fatal("expected the build-time gzip variant to be used")
+	}
+}
+
+func TestAssetStoreServesTheIdentityUntilCompressed(t *testing.T) {
+	static := fstest.MapFS{"app.js": {Data: []byte(strings.Repeat("console.log('supabase studio');\n", 200))}}
+	assets := newAssetStore(static)
+	asset := assets.lookup("app.js")
+	if asset == nil || asset.variants.Load() != nil {
+		t.Fatal("expected assets to be indexed without being read or compressed at startup")
+	}
+
+	// Hold every compression slot so the first request cannot wait for the variants.
+	for range cap(assets.slots) {
+		assets.slots <- struct{}{}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	rec := httptest.NewRecorder()
+	asset.serve(rec, req, "app.js")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "" || !bytes.Equal(rec.Body.Bytes(), static["app.js"].Data) {
+		t.Fatalf("expected the identity while compressing, got %d %v", rec.Code, rec.Header())
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected Vary on a compressible asset, got %v", rec.Header())
+	}
+
+	for range cap(assets.slots) {
+		<-assets.slots
+	}
+	<-asset.compressed
+	rec = httptest.NewRecorder()
+	asset.serve(rec, req, "app.js")
+	if rec.Header().Get("Content-Encoding") != "br" {
+		t.Fatalf("expected brotli once compressed, got %v", rec.Header())
+	}
+}
+
+func waitCompressed(t *testing.T, assets *assetStore, name string) *staticAsset {
+	t.Helper()
+	asset := assets.lookup(name)
+	if asset == nil {
+		t.Fatalf("expected %s to be an asset", name)
+	}
+	if err := asset.prepare(); err != nil {
+		t.Fatalf("prepare %s: %v", name, err)
+	}
+	<-asset.compressed
+	return asset
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	variants := map[string][]byte{"br": nil, "gzip": nil}
+	cases := map[string]string{
+		"":                     "",
+		"gzip":                 "gzip",
+		"gzip, br":             "br",
+		"br;q=0.5, gzip":       "gzip",
+		"br;q=0, gzip;q=0":     "",
+		"*":                    "br",
+		"zstd, identity":       "",
+		"GZIP;q=0.8, zstd;q=1": "gzip",
+	}
+	for header, want := range cases {
+		if got := negotiateEncoding(header, variants); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxLoginBodyBytes = 64 * 1024
+
+// studioAuth guards the dashboard itself. It is only enabled when a users file or an
+// OIDC issuer is configured.
+type studioAuth struct {
+	holder      *config.Holder
+	basePath    string
+	users       *auth.UserStore
+	oidc        *auth.OIDCProvider
+	redirectURL string
+	sessions    *auth.SessionManager
+}
+
+func newStudioAuth(holder *config.Holder, revocations auth.Revocations) *studioAuth {
+	cfg := holder.Get()
+	usersFile := strings.TrimSpace(cfg.StudioUsersFile)
+	issuer := strings.TrimSpace(cfg.StudioOIDCIssuer)
+	if usersFile == "" && issuer == "" {
+		return nil
+	}
+
+	var users *auth.UserStore
+	if usersFile != "" {
+		var err error
+		users, err = auth.NewUserStore(usersFile)
+		if err != nil {
+			slog.Error("failed to load studio users", "file", usersFile, "error", err)
+		}
+	}
+
+	var oidcProvider *auth.OIDCProvider
+	if issuer != "" {
+		oidcProvider = auth.NewOIDCProvider(auth.OIDCConfig{
+			Issuer:        issuer,
+			ClientID:      cfg.StudioOIDCClientID,
+			ClientSecret:  cfg.StudioOIDCClientSecret,
+			Scopes:        cfg.StudioOIDCScopes,
+			GroupsClaim:   cfg.StudioOIDCGroupsClaim,
+			AllowedGroups: cfg.StudioOIDCAllowedGroups,
+
+			AdminGroups:     cfg.StudioOIDCAdminGroups,
+			DeveloperGroups: cfg.StudioOIDCDeveloperGroups,
+			DefaultRole:     auth.Role(cfg.StudioOIDCDefaultRole),
+		})
+	}
+
+	if strings.TrimSpace(cfg.StudioSessionSecret) == "" {
+		slog.Warn("SUPABASE_STUDIO_GO_SESSION_SECRET is not set; sessions will not survive a restart")
+	}
+
+	basePath := strings.TrimSuffix(cfg.BasePath, "/")
+	cookiePath := basePath
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+
+	return &studioAuth{
+		holder:      holder,
+		basePath:    basePath,
+		users:       users,
+		oidc:        oidcProvider,
+		redirectURL: strings.TrimSpace(cfg.StudioOIDCRedirectURL),
+		sessions:    auth.NewSessionManager(cfg.StudioSessionSecret, cfg.StudioSessionTTL, cookiePath, revocations),
+	}
+}
+
+func (a *studioAuth) register(r chi.Router) {
+	r.Get("/auth/sign-in", a.handleSignInPage)
+	r.Post("/auth/sign-in", a.handleSignIn)
+	r.Post("/auth/logout", a.handleLogout)
+	r.Get("/auth/session", a.handleSession)
+	r.Get("/auth/oidc/login", a.handleOIDCLogin)
+	r.Get("/auth/oidc/callback", a.handleOIDCCallback)
+}
+
+func (a *studioAuth) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := a.trimBasePath(r.URL.Path)
+		if isPublicPath(path) || (path == "/metrics" && a.metricsTokenSet()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity, err := a.currentIdentity(r)
+		if err != nil {
+			a.rejectUnauthenticated(w, r, path)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+	})
+}
+
+// withoutLogin marks requests as served with dashboard authentication disabled.
+func withoutLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(auth.WithoutLogin(r.Context())))
+	})
+}
+
+func (a *studioAuth) currentIdentity(r *http.Request) (auth.Identity, error) {
+	identity, err := a.sessions.Identity(r)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	if identity.Provider == auth.ProviderOIDC {
+		if a.oidc == nil {
+			return auth.Identity{}, auth.ErrNoSession
+		}
+		return identity, nil
+	}
+	// Removing a user from the users file ends their existing sessions.
+	if a.users == nil {
+		return auth.Identity{}, auth.ErrUserNotFound
+	}
+	user, ok := a.users.Lookup(identity.Username)
+	if !ok {
+		return auth.Identity{}, auth.ErrUserNotFound
+	}
+	// Role changes in the users file apply to existing sessions too.
+	identity.Role = user.Identity().Role
+	return identity, nil
+}
+
+func (a *studioAuth) rejectUnauthenticated(w http.ResponseWriter, r *http.Request, path string) {
+	wantsPage := (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		!strings.HasPrefix(path, "/api/") &&
+		strings.Contains(r.Header.Get("Accept"), "text/html")
+	if !wantsPage {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{
+			"error": map[string]any{"message": "Authentication required"},
+		})
+		return
+	}
+
+	target := a.basePath + "/auth/sign-in?next=" + url.QueryEscape(r.URL.RequestURI())
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (a *studioAuth) handleSignInPage(w http.ResponseWriter, r *http.Request) {
+	if _, err := a.currentIdentity(r); err == nil {
+		http.Redirect(w, r, a.safeNext(r.URL.Query().Get("next")), http.StatusFound)
+		return
+	}
+	a.renderSignIn(w, http.StatusOK, r.URL.Query().Get("next"), "")
+}
+
+func (a *studioAuth) handleSignIn(w http.ResponseWriter, r *http.Request) {
+	if a.users == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]any{"message": "Password sign-in is not enabled"}})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxLoginBodyBytes)
+
+	var payload struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Next     string `json:"next"`
+	}
+	isJSON := strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+	if isJSON {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]any{"message": "Invalid request body"}})
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			a.renderSignIn(w, http.StatusBadRequest, "", "Invalid request")
+			return
+		}
+		payload.Username = r.PostForm.Get("username")
+		payload.Password = r.PostForm.Get("password")
+		payload.Next = r.PostForm.Get("next")
+	}
+
+	identity, err := a.users.Authenticate(payload.Username, payload.Password)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidCredentials) {
+			slog.ErrorContext(r.Context(), "studio sign-in failed", "error", err)
+		}
+		if isJSON {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": map[string]any{"message": "Invalid username or password"}})
+			return
+		}
+		a.renderSignIn(w, http.StatusUnauthorized, payload.Next, "Invalid username or password")
+		return
+	}
+
+	if err := a.sessions.Issue(w, r, identity); err != nil {
+		slog.ErrorContext(r.Context(), "failed to issue studio session", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": map[string]any{"message": "Failed to create session"}})
+		return
+	}
+
+	if isJSON {
+		writeJSON(w, http.StatusOK, map[string]any{"user": identity})
+		return
+	}
+	http.Redirect(w, r, a.safeNext(payload.Next), http.StatusSeeOther)
+}
+
+func (a *studioAuth) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if a.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state := auth.NewLoginState(r.URL.Query().Get("next"))
+	target, err := a.oidc.AuthCodeURL(r.Context(), a.oidcRedirectURL(r), state)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "studio single sign-on unavailable", "error", err)
+		a.renderSignIn(w, http.StatusBadGateway, state.Next, "Single sign-on is currently unavailable")
+		return
+	}
+	if err := a.sessions.IssueLoginState(w, r, state); err != nil {
+		slog.ErrorContext(r.Context(), "failed to store single sign-on state", "error", err)
+		a.renderSignIn(w, http.StatusInternalServerError, state.Next, "Failed to start single sign-on")
+		return
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (a *studioAuth) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if a.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, err := a.sessions.TakeLoginState(w, r)
+	query := r.URL.Query()
+	if err != nil || query.Get("state") == "" || query.Get("state") != state.State {
+		a.renderSignIn(w, http.StatusBadRequest, "", "Sign-in request expired, please try again")
+		return
+	}
+	if idpError := query.Get("error"); idpError != "" {
+		slog.WarnContext(r.Context(), "studio single sign-on rejected by identity provider", "error", idpError, "description", query.Get("error_description"))
+		a.renderSignIn(w, http.StatusUnauthorized, state.Next, "Single sign-on was cancelled or denied")
+		return
+	}
+
+	identity, err := a.oidc.Exchange(r.Context(), a.oidcRedirectURL(r), query.Get("code"), state)
+	if err != nil {
+		if errors.Is(err, auth.ErrAccessDenied) {
+			slog.WarnContext(r.Context(), "studio single sign-on denied: not in an allowed group", "user", identity.Username)
+			a.renderSignIn(w, http.StatusForbidden, state.Next, "Your account is not allowed to access Studio")
+			return
+		}
+		slog.WarnContext(r.Context(), "studio single sign-on failed", "error", err)
+		a.renderSignIn(w, http.StatusUnauthorized, state.Next, "Single sign-on failed")
+		return
+	}
+
+	if err := a.sessions.Issue(w, r, identity); err != nil {
+		slog.ErrorContext(r.Context(), "failed to issue studio session", "error", err)
+		a.renderSignIn(w, http.StatusInternalServerError, state.Next, "Failed to create session")
+		return
+	}
+	http.Redirect(w, r, a.safeNext(state.Next), http.StatusFound)
+}
+
+// oidcRedirectURL falls back to the callback on the host the request came in on.
+func (a *studioAuth) oidcRedirectURL(r *http.Request) string {
+	if a.redirectURL != "" {
+		return a.redirectURL
+	}
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + a.basePath + "/auth/oidc/callback"
+}
+
+func (a *studioAuth) handleLogout(w http.ResponseWriter, r *http.Request) {
+	a.sessions.Revoke(w, r)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Redirect(w, r, a.basePath+"/auth/sign-in", http.StatusSeeOther)
+}
+
+func (a *studioAuth) handleSession(w http.ResponseWriter, r *http.Request) {
+	identity, err := a.currentIdentity(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": map[string]any{"message": "Authentication required"}})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": identity})
+}
+
+func (a *studioAuth) renderSignIn(w http.ResponseWriter, status int, next, errorMessage string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	ssoURL := ""
+	if a.oidc != nil {
+		ssoURL = a.basePath + "/auth/oidc/login"
+		if next != "" {
+			ssoURL += "?next=" + url.QueryEscape(next)
+		}
+	}
+	_ = signInTemplate.Execute(w, map[string]any{
+		"Action":   a.basePath + "/auth/sign-in",
+		"Next":     next,
+		"Error":    errorMessage,
+		"Password": a.users != nil,
+		"SSOURL":   ssoURL,
+	})
+}
+
+// safeNext only allows redirects back into this site.
+func (a *studioAuth) safeNext(next string) string {
+	fallback := a.basePath + "/"
+	if next == "" || !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.Contains(next, "\\") {
+		return fallback
+	}
+	return next
+}
+
+func (a *studioAuth) trimBasePath(path string) string {
+	return stripBasePath(a.basePath, path)
+}
+
+func stripBasePath(basePath, path string) string {
+	if basePath == "" || !strings.HasPrefix(path, basePath) {
+		return path
+	}
+	trimmed := strings.TrimPrefix(path, basePath)
+	if trimmed == "" {
+		return "/"
+	}
+	return trimmed
+}
+
+func isPublicPath(path string) bool {
+	return path == "/healthz" || path == "/readyz" || path == "/csp-report" || strings.HasPrefix(path, "/auth/")
+}
+
+// metricsTokenSet reports whether /metrics is guarded by its own token, which lets scrapers
+// without a dashboard session past the login gate.
+func (a *studioAuth) metricsTokenSet() bool {
+	return strings.TrimSpace(a.holder.Get().MetricsToken) != ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+var signInTemplate = template.Must(template.New("sign-in").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in | Supabase Studio</title>
+<style>
+  body { margin: 0; min-height: 100vh; display: flex; align-items: center; justify-content: center; background: #1c1c1c; color: #ededed; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; }
+  form { width: 320px; padding: 32px; border: 1px solid #2e2e2e; border-radius: 8px; background: #232323; }
+  h1 { margin: 0 0 24px; font-size: 20px; font-weight: 500; }
+  label { display: block; margin-bottom: 6px; font-size: 13px; color: #a0a0a0; }
+  input { box-sizing: border-box; width: 100%; margin-bottom: 16px; padding: 8px 10px; border: 1px solid #3e3e3e; border-radius: 6px; background: #1c1c1c; color: #ededed; font-size: 14px; }
+  button { width: 100%; padding: 9px; border: 0; border-radius: 6px; background: #3ecf8e; color: #1c1c1c; font-size: 14px; font-weight: 500; cursor: pointer; }
+  .sso { display: block; box-sizing: border-box; width: 100%; padding: 9px; border: 1px solid #3e3e3e; border-radius: 6px; color: #ededed; font-size: 14px; text-align: center; text-decoration: none; }
+  .divider { margin: 16px 0; color: #707070; font-size: 12px; text-align: center; }
+  .error { margin-bottom: 16px; padding: 8px 10px; border-radius: 6px; background: #3b1919; color: #ff9592; font-size: 13px; }
+</style>
+</head>
+<body>
+<form method="post" action="{{.Action}}">
+  <h1>Sign in to Studio</h1>
+  {{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+  {{if .SSOURL}}<a class="sso" href="{{.SSOURL}}">Continue with single sign-on</a>{{end}}
+  {{if and .SSOURL .Password}}<div class="divider">or</div>{{end}}
+  {{if .Password}}
+  <input type="hidden" name="next" value="{{.Next}}">
+  <label for="username">Username</label>
+  <input id="username" name="username" autocomplete="username" autofocus required>
+  <label for="password">Password</label>
+  <input id="password" name="password" type="password" autocomplete="current-password" required>
+  <button type="submit">Sign in</button>
+  {{end}}
+</form>
+</body>
+</html>
+`))
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gouryella/supabase-studio-go/internal/auth"
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+func newAuthTestServer(t *testing.T, options ...func(*config.Config)) http.Handler {
+	t.Helper()
+
+	usersFile := filepath.Join(t.TempDir(), "users.json")
+	store, err := auth.NewUserStore(usersFile)
+	if err != nil {
+		t.Fatalf("failed to create user store: %v", err)
+	}
+	hash, err := auth.HashPassword("secret-password")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if err := store.Put(auth.User{Username: "alice", PasswordHash: hash, Role: auth.RoleAdmin}); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+
+	cfg := config.Config{
+		DefaultProjectName:       "Default Project",
+		DefaultProjectDiskSizeGB: 8,
+		StudioUsersFile:          usersFile,
+		StudioSessionSecret:      "test-session-secret",
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return New(t.Context(), config.NewHolder(cfg, ""))
+}
+
+func TestStudioAuthRejectsAnonymousAPIRequests(t *testing.T) {
+	handler := newAuthTestServer(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/platform/projects/default/settings", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "jwt_secret") {
+		t.Fatalf("expected project settings not to leak, got %s", rec.Body.String())
+	}
+}
+
+func TestStudioAuthKeepsMetricsBehindTheGateWithoutToken(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newAuthTestServer(t).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous /metrics to be rejected, got %d", rec.Code)
+	}
+
+	handler := newAuthTestServer(t, func(cfg *config.Config) { cfg.MetricsToken = "scrape-token" })
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-token")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected a scraper with the metrics token to pass the gate, got %d", rec.Code)
+	}
+}
+
+func TestStudioAuthRedirectsAnonymousPagesToSignIn(t *testing.T) {
+	handler := newAuthTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/project/default", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d", rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "/auth/sign-in?next=%2Fproject%2Fdefault" {
+		t.Fatalf("unexpected redirect location %q", location)
+	}
+}
+
+func TestStudioAuthSignInGrantsAccessUntilLogout(t *testing.T) {
+	handler := newAuthTestServer(t)
+
+	form := url.Values{"username": {"alice"}, "password": {"secret-password"}, "next": {"/project/default"}}
+	signInReq := httptest.NewRequest(http.MethodPost, "/auth/sign-in", strings.NewReader(form.Encode()))
+	signInReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	signInRec := httptest.NewRecorder()
+	handler.ServeHTTP(signInRec, signInReq)
+
+	if signInRec.Code != http.StatusSeeOther {
+		t.Fatalf("expected status 303, got %d with body %s", signInRec.Code, signInRec.Body.String())
+	}
+	if location := signInRec.Header().Get("Location"); location != "/project/default" {
+		t.Fatalf("unexpected redirect location %q", location)
+	}
+	cookies := signInRec.Result().Cookies()
+
+	withCookies := func(req *http.Request) *http.Request {
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, withCookies(httptest.NewRequest(http.MethodGet, "/api/platform/projects/default", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected authenticated request to succeed, got %d", rec.Code)
+	}
+
+	// A cross-site link or image must not be able to sign the user out.
+	handler.ServeHTTP(httptest.NewRecorder(), withCookies(httptest.NewRequest(http.MethodGet, "/auth/logout", nil)))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withCookies(httptest.NewRequest(http.MethodGet, "/api/platform/projects/default", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected GET /auth/logout to keep the session, got %d", rec.Code)
+	}
+
+	logoutRec := httptest.NewRecorder()
+	handler.ServeHTTP(logoutRec, withCookies(httptest.NewRequest(http.MethodPost, "/auth/logout", nil)))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withCookies(httptest.NewRequest(http.MethodGet, "/api/platform/projects/default", nil)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected request after logout to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestStudioAuthRejectsWrongPassword(t *testing.T) {
+	handler := newAuthTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/sign-in", strings.NewReader(`{"username":"alice","password":"nope"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rec.Code)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("expected no session cookie on failed sign-in")
+	}
+}
+
+func TestStudioAuthKeepsHealthzPublic(t *testing.T) {
+	handler := newAuthTestServer(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+func TestCORSPolicyAnswersPreflightForAllowedOrigins(t *testing.T) {
+	nextCalled := false
+	handler := corsPolicy(config.Config{
+		CORSAllowedOrigins:   []string{"https://admin.example.com"},
+		CORSAllowedMethods:   []string{"GET", "POST"},
+		CORSAllowedHeaders:   []string{"Content-Type"},
+		CORSAllowCredentials: true,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/api/platform/projects", nil)
+	preflight.Header.Set("Origin", "https://admin.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, preflight)
+
+	if rec.Code != http.StatusNoContent || nextCalled {
+		t.Fatalf("expected the preflight to be answered directly, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+		t.Fatalf("expected the origin to be echoed, got %q", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "true" || rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST" {
+		t.Fatalf("unexpected preflight headers: %v", rec.Header())
+	}
+
+	other := httptest.NewRequest(http.MethodGet, "/api/platform/projects", nil)
+	other.Header.Set("Origin", "https://evil.example")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, other)
+	if !nextCalled || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected no CORS headers for other origins, got %v", rec.Header())
+	}
+}
+
+func TestCORSPolicyWildcardNeverAllowsCredentials(t *testing.T) {
+	handler := corsPolicy(config.Config{
+		CORSAllowedOrigins:   []string{"*"},
+		CORSAllowCredentials: true,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/get-utc-time", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("unexpected headers for wildcard origin: %v", rec.Header())
+	}
+}
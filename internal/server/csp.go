@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+const (
+	cspReportOnly = "report-only"
+	cspOff        = "off"
+
+	maxCSPReportBytes = 64 * 1024
+)
+
+// contentSecurityPolicy renders the policy for one request. The Studio frontend is a static
+// export, so the policy mirrors frontend/csp.js for a self-hosted deployment: scripts come
+// from this origin or carry the request's nonce, and the browser talks directly only to the
+// Supabase API gateway.
+type contentSecurityPolicy struct {
+	header string
+	// directives holds everything but script-src, which needs the nonce.
+	directives []string
+	scriptSrc  []string
+	// enforced is sent alongside a report-only policy, which browsers ignore frame-ancestors in.
+	enforced string
+}
+
+func newContentSecurityPolicy(cfg config.Config) *contentSecurityPolicy {
+	mode := strings.ToLower(strings.TrimSpace(cfg.CSPMode))
+	if mode == cspOff {
+		return nil
+	}
+
+	policy := &contentSecurityPolicy{header: "Content-Security-Policy"}
+	if mode == cspReportOnly {
+		policy.header = "Content-Security-Policy-Report-Only"
+	}
+
+	var gateway, gatewaySockets []string
+	for _, raw := range []string{cfg.SupabasePublicURL, cfg.SupabaseURL} {
+		parsed, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || parsed.Host == "" || slices.Contains(gateway, parsed.Scheme+"://"+parsed.Host) {
+			continue
+		}
+		gateway = append(gateway, parsed.Scheme+"://"+parsed.Host)
+		gatewaySockets = append(gatewaySockets, "ws://"+parsed.Host, "wss://"+parsed.Host)
+	}
+
+	frameAncestors := cfg.CSPFrameAncestors
+	if len(frameAncestors) == 0 {
+		frameAncestors = []string{"'none'"}
+	}
+
+	directive := func(name string, sources ...[]string) string {
+		return name + " " + strings.Join(slices.Concat(sources...), " ")
+	}
+	policy.scriptSrc = slices.Concat([]string{"'self'", "'unsafe-eval'"}, cfg.CSPScriptSources)
+	policy.directives = []string{
+		"default-src 'self'",
+		directive("connect-src", []string{"'self'"}, gateway, gatewaySockets, cfg.CSPConnectSources),
+		directive("img-src", []string{"'self'", "blob:", "data:"}, gateway, cfg.CSPImageSources),
+		directive("style-src", []string{"'self'", "'unsafe-inline'"}, cfg.CSPStyleSources),
+		directive("font-src", []string{"'self'", "data:"}, cfg.CSPFontSources),
+		directive("frame-src", []string{"'self'"}, cfg.CSPFrameSources),
+		"worker-src 'self' blob: data:",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		directive("frame-ancestors", frameAncestors),
+		"report-uri " + strings.TrimSuffix(cfg.BasePath, "/") + "/csp-report",
+	}
+	if mode == cspReportOnly {
+		policy.enforced = directive("frame-ancestors", frameAncestors)
+	}
+	return policy
+}
+
+func (p *contentSecurityPolicy) render(nonce string) string {
+	scriptSrc := "script-src " + strings.Join(p.scriptSrc, " ") + " 'nonce-" + nonce + "'"
+	return strings.Join(append([]string{p.directives[0], scriptSrc}, p.directives[1:]...), "; ")
+}
+
+// frameOptions is the X-Frame-Options equivalent of the frame-ancestors setting, for
+// browsers without CSP level 2. Lists of origins have no equivalent.
+func frameOptions(cfg config.Config) string {
+	switch strings.Join(cfg.CSPFrameAncestors, " ") {
+	case "", "'none'":
+		return "DENY"
+	case "'self'":
+		return "SAMEORIGIN"
+	}
+	return ""
+}
+
+type nonceContextKey struct{}
+
+func newNonce() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+func withNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, nonceContextKey{}, nonce)
+}
+
+func nonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceContextKey{}).(string)
+	return nonce
+}
+
+var scriptTagPattern = regexp.MustCompile(`(?i)<script\b`)
+
+// injectNonce adds the nonce to every script tag of an HTML page, so that the inline
+// bootstrap scripts of the static export pass the policy.
+func injectNonce(page []byte, nonce string) []byte {
+	if nonce == "" {
+		return page
+	}
+	return scriptTagPattern.ReplaceAll(page, []byte(`${0} nonce="`+nonce+`"`))
+}
+
+// handleCSPReport logs violations sent by browsers, in both the report-uri format and the
+// Reporting API format. Use it with report-only mode to tune the policy before enforcing it.
+func handleCSPReport(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var reports []map[string]any
+	var legacy struct {
+		Report map[string]any `json:"csp-report"`
+	}
+	var batch []struct {
+		Type string         `json:"type"`
+		Body map[string]any `json:"body"`
+	}
+	switch trimmed := bytes.TrimSpace(body); {
+	case bytes.HasPrefix(trimmed, []byte("[")) && json.Unmarshal(trimmed, &batch) == nil:
+		for _, entry := range batch {
+			if entry.Type == "csp-violation" && entry.Body != nil {
+				reports = append(reports, entry.Body)
+			}
+		}
+	case json.Unmarshal(trimmed, &legacy) == nil && legacy.Report != nil:
+		reports = append(reports, legacy.Report)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, report := range reports {
+		slog.WarnContext(r.Context(), "content security policy violation",
+			"document", firstString(report, "document-uri", "documentURL"),
+			"directive", firstString(report, "effective-directive", "effectiveDirective", "violated-directive"),
+			"blocked", firstString(report, "blocked-uri", "blockedURL"),
+			"source", firstString(report, "source-file", "sourceFile"),
+			"line", report["line-number"],
+			"disposition", firstString(report, "disposition"),
+		)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func firstString(values map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := values[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+func TestSecurityHeadersSendNoncedPolicyIntoServedPages(t *testing.T) {
+	static := fstest.MapFS{
+		"index.html": {Data: []byte(`<html><head><script src="/_next/static/app.js"></script><script>self.__next_f=[]</script></head></html>`)},
+	}
+	handler := securityHeaders(config.Config{
+		CSPMode:           "enforce",
+		CSPFrameAncestors: []string{"'self'"},
+		CSPConnectSources: []string{"https://api.openai.com"},
+		SupabasePublicURL: "https://db.example.com:8443/",
+	})(spaHandler(t.Context(), static, false, nil, config.NewHolder(config.Config{}, "")))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	policy := rec.Header().Get("Content-Security-Policy")
+	start := strings.Index(policy, "'nonce-")
+	if start < 0 {
+		t.Fatalf("expected a nonce in the policy, got %q", policy)
+	}
+	nonce := policy[start+len("'nonce-"):]
+	nonce = nonce[:strings.Index(nonce, "'")]
+
+	for _, want := range []string{
+		"connect-src 'self' https://db.example.com:8443 ws://db.example.com:8443 wss://db.example.com:8443 https://api.openai.com",
+		"frame-ancestors 'self'",
+		"report-uri /csp-report",
+	} {
+		if !strings.Contains(policy, want) {
+			t.Fatalf("expected %q in the policy, got %q", want, policy)
+		}
+	}
+	if rec.Header().Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Fatalf("expected X-Frame-Options to follow frame-ancestors, got %q", rec.Header().Get("X-Frame-Options"))
+	}
+	if got := strings.Count(rec.Body.String(), `nonce="`+nonce+`"`); got != 3 {
+		t.Fatalf("expected the page's and the inlined env script tags to carry the nonce, got %d in %s", got, rec.Body.String())
+	}
+
+	again := httptest.NewRecorder()
+	handler.ServeHTTP(again, httptest.NewRequest(http.MethodGet, "/", nil))
+	if again.Header().Get("Content-Security-Policy") == policy {
+		t.Fatal("expected a fresh nonce per request")
+	}
+}
+
+func TestSecurityHeadersReportOnlyMode(t *testing.T) {
+	handler := securityHeaders(config.Config{CSPMode: "report-only", BasePath: "/studio"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/studio", nil))
+
+	if policy := rec.Header().Get("Content-Security-Policy"); policy != "frame-ancestors 'none'" {
+		t.Fatalf("expected only frame-ancestors to be enforced in report-only mode, got %q", policy)
+	}
+	if policy := rec.Header().Get("Content-Security-Policy-Report-Only"); !strings.Contains(policy, "report-uri /studio/csp-report") {
+		t.Fatalf("unexpected report-only policy %q", policy)
+	}
+	if rec.Header().Get("X-Frame-Options") != "DENY" {
+		t.Fatalf("expected frames to be denied by default, got %q", rec.Header().Get("X-Frame-Options"))
+	}
+}
+
+func TestCSPReportLogsViolations(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"report-uri", `{"csp-report":{"document-uri":"https://studio.local/project/default","effective-directive":"script-src-elem","blocked-uri":"https://cdn.evil.example/x.js"}}`, http.StatusNoContent},
+		{"reporting api", `[{"type":"csp-violation","body":{"documentURL":"https://studio.local/","effectiveDirective":"connect-src","blockedURL":"wss://realtime.example.com"}}]`, http.StatusNoContent},
+		{"garbage", `not json`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleCSPReport(rec, httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(tc.body)))
+			if rec.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+
+	for _, want := range []string{"blocked=https://cdn.evil.example/x.js", "directive=connect-src"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("expected %q in the logs, got %s", want, logs.String())
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+func TestCSRFProtectionRefusesCrossSiteUnsafeRequests(t *testing.T) {
+	handler := csrfProtection(config.Config{
+		BasePath:           "/studio",
+		CSRFTrustedOrigins: []string{"https://admin.example.com"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{"safe method", http.MethodGet, "/studio/api/platform/projects", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, http.StatusNoContent},
+		{"same origin fetch", http.MethodPost, "/studio/api/platform/pg-meta/default/query", map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://studio.local"}, http.StatusNoContent},
+		{"cross site fetch", http.MethodPost, "/studio/api/platform/pg-meta/default/query", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, http.StatusForbidden},
+		{"same site subdomain", http.MethodDelete, "/studio/api/platform/storage/default/buckets/avatars", map[string]string{"Sec-Fetch-Site": "same-site", "Origin": "https://other.studio.local"}, http.StatusForbidden},
+		{"trusted origin", http.MethodPost, "/studio/api/platform/pg-meta/default/query", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://admin.example.com"}, http.StatusNoContent},
+		{"origin matching host without fetch metadata", http.MethodPatch, "/studio/api/platform/projects/default", map[string]string{"Origin": "http://studio.local"}, http.StatusNoContent},
+		{"foreign origin without fetch metadata", http.MethodPatch, "/studio/api/platform/projects/default", map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"opaque origin", http.MethodPost, "/studio/auth/sign-in", map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"non-browser client", http.MethodPost, "/studio/api/platform/pg-meta/default/query", nil, http.StatusNoContent},
+		{"cross site token API", http.MethodPost, "/studio/api/v1/projects/default/database/migrations", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example", "Authorization": "Bearer sbp_token"}, http.StatusForbidden},
+		{"csp report", http.MethodPost, "/studio/csp-report", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, http.StatusNoContent},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "http://studio.local"+tc.path, nil)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestBearerOnlyRequestsNeedASessionWithLoginEnabled(t *testing.T) {
+	handler := newAuthTestServer(t)
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"cross site", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, http.StatusForbidden},
+		{"non-browser", nil, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/projects/default/database/migrations", strings.NewReader(`{"query":"select 1"}`))
+		req.Header.Set("Authorization", "Bearer sbp_token")
+		for key, value := range tc.headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected a bearer-only request to get %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+}
@@ -2,79 +2,38 @@ package server
 
 import (
 	"encoding/json"
+	"maps"
 	"net/http"
-	"os"
-	"strings"
+	"slices"
 
 	"github.com/Gouryella/supabase-studio-go/internal/config"
 )
 
-var allowedPublicEnvKeys = map[string]struct{}{
-	"NEXT_PUBLIC_API_URL":                          {},
-	"NEXT_PUBLIC_AUTH_DEBUG_KEY":                   {},
-	"NEXT_PUBLIC_AUTH_DEBUG_PERSISTED_KEY":         {},
-	"NEXT_PUBLIC_AUTH_DETECT_SESSION_IN_URL":       {},
-	"NEXT_PUBLIC_AUTH_NAVIGATOR_LOCK_KEY":          {},
-	"NEXT_PUBLIC_BASE_PATH":                        {},
-	"NEXT_PUBLIC_CONFIGCAT_PROXY_URL":              {},
-	"NEXT_PUBLIC_CONFIGCAT_SDK_KEY":                {},
-	"NEXT_PUBLIC_CONTENT_API_URL":                  {},
-	"NEXT_PUBLIC_DISABLED_FEATURES":                {},
-	"NEXT_PUBLIC_DOCS_URL":                         {},
-	"NEXT_PUBLIC_ENVIRONMENT":                      {},
-	"NEXT_PUBLIC_GITHUB_INTEGRATION_APP_NAME":      {},
-	"NEXT_PUBLIC_GITHUB_INTEGRATION_CLIENT_ID":     {},
-	"NEXT_PUBLIC_GOOGLE_MAPS_KEY":                  {},
-	"NEXT_PUBLIC_GOOGLE_TAG_MANAGER_ID":            {},
-	"NEXT_PUBLIC_IS_NIMBUS":                        {},
-	"NEXT_PUBLIC_IS_PLATFORM":                      {},
-	"NEXT_PUBLIC_MCP_URL":                          {},
-	"NEXT_PUBLIC_NODE_ENV":                         {},
-	"NEXT_PUBLIC_ONGOING_INCIDENT":                 {},
-	"NEXT_PUBLIC_POSTHOG_HOST":                     {},
-	"NEXT_PUBLIC_POSTHOG_KEY":                      {},
-	"NEXT_PUBLIC_POSTHOG_UI_HOST":                  {},
-	"NEXT_PUBLIC_SENTRY_DSN":                       {},
-	"NEXT_PUBLIC_SENTRY_ENVIRONMENT":               {},
-	"NEXT_PUBLIC_SITE_URL":                         {},
-	"NEXT_PUBLIC_STORAGE_KEY":                      {},
-	"NEXT_PUBLIC_STRIPE_PUBLIC_KEY":                {},
-	"NEXT_PUBLIC_SUPABASE_ANON_KEY":                {},
-	"NEXT_PUBLIC_SUPABASE_PUBLISHABLE_DEFAULT_KEY": {},
-	"NEXT_PUBLIC_SUPABASE_URL":                     {},
-	"NEXT_PUBLIC_SUPPORT_ANON_KEY":                 {},
-	"NEXT_PUBLIC_SUPPORT_API_URL":                  {},
-	"NEXT_PUBLIC_USERCENTRICS_RULESET_ID":          {},
-	"NEXT_PUBLIC_VERCEL_BRANCH_URL":                {},
-	"NEXT_PUBLIC_VERCEL_ENV":                       {},
-}
-
-func envHandler(cfg config.Config) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		env := make(map[string]string)
-
-		for _, pair := range os.Environ() {
-			key, value, found := strings.Cut(pair, "=")
-			if !found || key == "" {
-				continue
-			}
-			if _, allowed := allowedPublicEnvKeys[key]; allowed {
-				env[key] = value
-			}
+// publicEnvScript renders the browser's window.__env from cfg.PublicEnv.
+func publicEnvScript(cfg config.Config) []byte {
+	env := maps.Clone(cfg.PublicEnv)
+	if env == nil {
+		env = map[string]string{}
+	}
+	if _, exists := env["NEXT_PUBLIC_IS_PLATFORM"]; !exists {
+		env["NEXT_PUBLIC_IS_PLATFORM"] = "false"
+		if cfg.IsPlatform {
+			env["NEXT_PUBLIC_IS_PLATFORM"] = "true"
 		}
+	}
 
-		if _, exists := env["NEXT_PUBLIC_IS_PLATFORM"]; !exists {
-			env["NEXT_PUBLIC_IS_PLATFORM"] = "false"
-			if cfg.IsPlatform {
-				env["NEXT_PUBLIC_IS_PLATFORM"] = "true"
-			}
-		}
+	// json.Marshal escapes "<", so no value can close an inline script.
+	payload, _ := json.Marshal(env)
+	return slices.Concat([]byte("window.__env = "), payload, []byte(";"))
+}
 
-		payload, _ := json.Marshal(env)
+// envHandler serves the script for pages that still load it; pages served by spaHandler
+// have it inlined. It follows reloads of the configuration.
+func envHandler(holder *config.Holder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/javascript")
+		w.Header().Set("Cache-Control", "no-cache")
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("window.__env = "))
-		w.Write(payload)
-		w.Write([]byte(";"))
+		_, _ = w.Write(publicEnvScript(holder.Get()))
 	}
 }
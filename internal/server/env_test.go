@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/Gouryella/supabase-studio-go/internal/config"
 )
@@ -21,7 +24,7 @@ func TestEnvHandlerFiltersSensitiveKeys(t *testing.T) {
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/env.js", nil)
 
-	envHandler(config.Config{IsPlatform: false}).ServeHTTP(rec, req)
+	envHandler(config.NewHolder(config.Load(), "")).ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
@@ -61,3 +64,89 @@ func TestEnvHandlerFiltersSensitiveKeys(t *testing.T) {
 		t.Errorf("expected NEXT_PUBLIC_IS_PLATFORM=false, got %q", env["NEXT_PUBLIC_IS_PLATFORM"])
 	}
 }
+
+func TestEnvHandlerExposesConfiguredKeysButNeverSecrets(t *testing.T) {
+	t.Setenv("NEXT_PUBLIC_FEATURE_FLAGS", "sso,branching")
+	t.Setenv("NEXT_PUBLIC_STRIPE_SECRET", "should-not-leak")
+	t.Setenv("INTERNAL_HOSTNAME", "db.internal")
+	t.Setenv("SUPABASE_STUDIO_GO_PUBLIC_ENV_KEYS", "NEXT_PUBLIC_FEATURE_FLAGS,NEXT_PUBLIC_STRIPE_SECRET,INTERNAL_HOSTNAME")
+
+	rec := httptest.NewRecorder()
+	envHandler(config.NewHolder(config.Load(), "")).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/env.js", nil))
+
+	body := strings.TrimSuffix(strings.TrimPrefix(rec.Body.String(), "window.__env = "), ";")
+	var env map[string]string
+	if err := json.Unmarshal([]byte(body), &env); err != nil {
+		t.Fatalf("failed to parse env payload: %v", err)
+	}
+	if env["NEXT_PUBLIC_FEATURE_FLAGS"] != "sso,branching" {
+		t.Errorf("expected the configured key to be exposed, got %v", env)
+	}
+	for _, key := range []string{"NEXT_PUBLIC_STRIPE_SECRET", "INTERNAL_HOSTNAME"} {
+		if _, exists := env[key]; exists {
+			t.Errorf("expected %s to be filtered", key)
+		}
+	}
+}
+
+func TestSPAHandlerInlinesEnvAndRewritesBasePath(t *testing.T) {
+	static := fstest.MapFS{
+		"index.html": {Data: []byte(`<html><head><script src="/env.js"></script><link rel="icon" href="/favicon/favicon.ico"><link rel="preconnect" href="//fonts.example"></head>` +
+			`<body><script src="/studio/_next/static/main.js"></script><script src="/_next/static/app.js"></script></body></html>`)},
+	}
+	handler := spaHandler(t.Context(), static, false, nil, config.NewHolder(config.Config{
+		BasePath:  "/studio/",
+		PublicEnv: map[string]string{"NEXT_PUBLIC_SUPABASE_URL": "https://example.supabase.co"},
+	}, ""))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/studio/", nil))
+	body := rec.Body.String()
+
+	if strings.Contains(body, "env.js") || !strings.Contains(body, `<script>window.__env = {`) || !strings.Contains(body, `"NEXT_PUBLIC_SUPABASE_URL":"https://example.supabase.co"`) {
+		t.Fatalf("expected window.__env to be inlined, got %s", body)
+	}
+	for _, want := range []string{`href="/studio/favicon/favicon.ico"`, `href="//fonts.example"`, `src="/studio/_next/static/main.js"`, `src="/studio/_next/static/app.js"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in %s", want, body)
+		}
+	}
+}
+
+func TestEnvFollowsTheConfigFileAcrossReloads(t *testing.T) {
+	t.Setenv("NEXT_PUBLIC_SITE_URL", "")
+	path := filepath.Join(t.TempDir(), "studio.yaml")
+	if err := os.WriteFile(path, []byte("next_public_site_url: https://studio.example.com\n"), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	cfg, _, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	holder := config.NewHolder(cfg, path)
+	static := fstest.MapFS{"index.html": {Data: []byte(`<html><head><script src="/env.js"></script></head><body></body></html>`)}}
+	handler := spaHandler(t.Context(), static, false, nil, holder)
+
+	get := func(handler http.Handler, target string) string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Body.String()
+	}
+	for _, body := range []string{get(envHandler(holder), "/env.js"), get(handler, "/")} {
+		if !strings.Contains(body, `"NEXT_PUBLIC_SITE_URL":"https://studio.example.com"`) {
+			t.Fatalf("expected the value from the config file, got %s", body)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("next_public_site_url: https://dashboard.example.com\n"), 0o600); err != nil {
+		t.Fatalf("failed to rewrite config file: %v", err)
+	}
+	if _, _, err := holder.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	for _, body := range []string{get(envHandler(holder), "/env.js"), get(handler, "/")} {
+		if !strings.Contains(body, `"NEXT_PUBLIC_SITE_URL":"https://dashboard.example.com"`) {
+			t.Fatalf("expected the reloaded value, got %s", body)
+		}
+	}
+}
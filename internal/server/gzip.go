@@ -7,11 +7,14 @@ import (
 	"github.com/NYTimes/gziphandler"
 )
 
-func gzipMiddleware() func(http.Handler) http.Handler {
+// gzipMiddleware compresses responses on the fly, except for the static assets, which are
+// served precompressed.
+func gzipMiddleware(assets *assetStore, basePath string) func(http.Handler) http.Handler {
+	base := strings.TrimSuffix(basePath, "/")
 	return func(next http.Handler) http.Handler {
 		gzipHandler := gziphandler.GzipHandler(next)
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if shouldBypassGzip(r) {
+			if shouldBypassGzip(r) || assets.lookup(strings.TrimPrefix(stripBasePath(base, r.URL.Path), "/")) != nil {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+var (
+	envScriptTagPattern = regexp.MustCompile(`(?is)<script\b[^>]*\bsrc="[^"]*/env\.js"[^>]*>\s*</script>`)
+	headTagPattern      = regexp.MustCompile(`(?i)<head\b[^>]*>`)
+	rootURLAttrPattern  = regexp.MustCompile(`\s(?:src|href)="(/[^"]*)"`)
+)
+
+// htmlPages serves the frontend's pages with window.__env inlined, so the browser needs no
+// request for /env.js before booting, and with root-relative URLs moved under the runtime
+// base path. Prepared pages are cached unless the frontend is read from disk; window.__env,
+// which follows reloads of the configuration, and the CSP nonce are added per request.
+type htmlPages struct {
+	static   fs.FS
+	basePath string
+	holder   *config.Holder
+	cache    bool
+
+	mu       sync.Mutex
+	prepared map[string][]byte
+}
+
+func newHTMLPages(static fs.FS, holder *config.Holder, cache bool) *htmlPages {
+	return &htmlPages{
+		static:   static,
+		basePath: strings.TrimSuffix(holder.Get().BasePath, "/"),
+		holder:   holder,
+		cache:    cache,
+		prepared: make(map[string][]byte),
+	}
+}
+
+func (p *htmlPages) serve(w http.ResponseWriter, r *http.Request, name string, status int) {
+	page, err := p.page(name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	page = injectNonce(inlineEnvScript(page, publicEnvScript(p.holder.Get())), nonceFromContext(r.Context()))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if status == http.StatusOK {
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(page))
+		return
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(page)
+	}
+}
+
+func (p *htmlPages) page(name string) ([]byte, error) {
+	if p.cache {
+		p.mu.Lock()
+		page, ok := p.prepared[name]
+		p.mu.Unlock()
+		if ok {
+			return page, nil
+		}
+	}
+
+	page, err := fs.ReadFile(p.static, name)
+	if err != nil {
+		return nil, err
+	}
+	// The env script is inlined after this, so its values are never rewritten.
+	page = rewriteBasePath(page, p.basePath)
+
+	if p.cache {
+		p.mu.Lock()
+		p.prepared[name] = page
+		p.mu.Unlock()
+	}
+	return page, nil
+}
+
+// inlineEnvScript replaces the page's /env.js script tag with the script itself, or adds
+// it at the top of <head> for pages without one.
+func inlineEnvScript(page, script []byte) []byte {
+	tag := slices.Concat([]byte("<script>"), script, []byte("</script>"))
+	if loc := envScriptTagPattern.FindIndex(page); loc != nil {
+		return slices.Concat(page[:loc[0]], tag, page[loc[1]:])
+	}
+	if loc := headTagPattern.FindIndex(page); loc != nil {
+		return slices.Concat(page[:loc[1]], tag, page[loc[1]:])
+	}
+	return slices.Concat(tag, page)
+}
+
+// rewriteBasePath prefixes root-relative src and href URLs with basePath, for a frontend
+// exported without the base path the server runs under.
+func rewriteBasePath(page []byte, basePath string) []byte {
+	if basePath == "" {
+		return page
+	}
+	var out []byte
+	last := 0
+	for _, match := range rootURLAttrPattern.FindAllSubmatchIndex(page, -1) {
+		url := string(page[match[2]:match[3]])
+		if strings.HasPrefix(url, "//") || url == basePath || strings.HasPrefix(url, basePath+"/") {
+			continue
+		}
+		out = append(out, page[last:match[2]]...)
+		out = append(out, basePath...)
+		last = match[2]
+	}
+	return append(out, page[last:]...)
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/metrics"
+)
+
+// metricsHandler serves Prometheus metrics, guarded by an optional bearer token. Scrapers
+// have no dashboard session, so with the login gate enabled the endpoint bypasses it only
+// while a token is set.
+func metricsHandler(holder *config.Holder) http.HandlerFunc {
+	handler := metrics.Handler()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := strings.TrimSpace(holder.Get().MetricsToken); token != "" {
+			provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				writeJSON(w, http.StatusUnauthorized, map[string]any{
+					"error": map[string]any{"message": "Invalid metrics token"},
+				})
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}
+}
@@ -9,9 +9,14 @@ import (
 )
 
 func securityHeaders(cfg config.Config) func(http.Handler) http.Handler {
+	policy := newContentSecurityPolicy(cfg)
+	frameOption := frameOptions(cfg)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("X-Frame-Options", "DENY")
+			if frameOption != "" {
+				w.Header().Set("X-Frame-Options", frameOption)
+			}
 			w.Header().Set("X-Content-Type-Options", "no-sniff")
 			w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
 
@@ -19,7 +24,14 @@ func securityHeaders(cfg config.Config) func(http.Handler) http.Handler {
 				w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload")
 			}
 
-			w.Header().Set("Content-Security-Policy", "frame-ancestors 'none';")
+			if policy != nil {
+				nonce := newNonce()
+				w.Header().Set(policy.header, policy.render(nonce))
+				if policy.enforced != "" {
+					w.Header().Set("Content-Security-Policy", policy.enforced)
+				}
+				r = r.WithContext(withNonce(r.Context(), nonce))
+			}
 
 			next.ServeHTTP(w, r)
 		})
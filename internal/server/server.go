@@ -1,30 +1,58 @@
 package server
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/Gouryella/supabase-studio-go/internal/api"
 	"github.com/Gouryella/supabase-studio-go/internal/config"
+	"github.com/Gouryella/supabase-studio-go/internal/logging"
+	"github.com/Gouryella/supabase-studio-go/internal/metrics"
+	"github.com/Gouryella/supabase-studio-go/internal/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func New(cfg config.Config) http.Handler {
-	static, err := staticFS()
+// New builds the HTTP handler. Listener, routing and login settings are fixed at startup;
+// API handlers read everything else from holder on each request. Background work started
+// for the handler stops when ctx is done.
+func New(ctx context.Context, holder *config.Holder) http.Handler {
+	cfg := holder.Get()
+	static, fromDisk, err := frontendFS(cfg)
 	if err != nil {
-		log.Printf("failed to load embedded static assets: %v", err)
+		slog.Error("failed to load embedded static assets", "error", err)
 	}
 
+	// A frontend on disk changes while the server runs, so it is compressed per request.
+	// The embedded one is compressed once per asset, on its first request.
+	var assets *assetStore
+	if static != nil && !fromDisk {
+		assets = newAssetStore(static)
+	}
+
+	apiServer := api.New(ctx, holder)
+	studioAuth := newStudioAuth(holder, apiServer.Revocations())
+
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
+	router.Use(tracing.Middleware)
+	router.Use(logging.AccessLog)
+	router.Use(metrics.Middleware)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.Timeout(120 * time.Second))
 	router.Use(securityHeaders(cfg))
-	router.Use(gzipMiddleware())
+	router.Use(gzipMiddleware(assets, cfg.BasePath))
+	router.Use(corsPolicy(cfg))
+	router.Use(csrfProtection(cfg))
+	if studioAuth != nil {
+		router.Use(studioAuth.middleware)
+	} else {
+		router.Use(withoutLogin)
+	}
 
 	registerRedirects(router, cfg)
 
@@ -34,12 +62,20 @@ func New(cfg config.Config) http.Handler {
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
 
-	router.Get("/env.js", envHandler(cfg))
+	router.Method(http.MethodGet, "/readyz", api.NewReadinessHandler(holder))
+
+	router.Get("/env.js", envHandler(holder))
+	router.Get("/metrics", metricsHandler(holder))
+	router.Post("/csp-report", handleCSPReport)
+
+	if studioAuth != nil {
+		studioAuth.register(router)
+	}
 
-	router.Mount("/api", api.NewRouter(cfg))
+	router.Mount("/api", apiServer.Router())
 
 	if static != nil {
-		router.NotFound(spaHandler(static, cfg))
+		router.NotFound(spaHandler(ctx, static, fromDisk, assets, holder))
 	}
 
 	if cfg.BasePath != "" {
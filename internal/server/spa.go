@@ -1,18 +1,31 @@
 package server
 
 import (
+	"context"
 	"io/fs"
 	"net/http"
 	"path"
 	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/Gouryella/supabase-studio-go/internal/config"
 )
 
-func spaHandler(static fs.FS, cfg config.Config) http.HandlerFunc {
+// spaHandler serves the exported frontend. fromDisk marks a frontend read from
+// FrontendDir, which may change while the server runs; its routes are rescanned until ctx
+// is done.
+func spaHandler(ctx context.Context, static fs.FS, fromDisk bool, assets *assetStore, holder *config.Holder) http.HandlerFunc {
+	cfg := holder.Get()
 	fileServer := http.FileServer(http.FS(static))
-	dynamicRoutes := buildDynamicRoutes(static)
+	var routes *routeTable
+	if fromDisk {
+		routes = watchRouteTable(ctx, static, cfg.FrontendRescanInterval)
+	} else {
+		routes = newRouteTable(static)
+	}
+	pages := newHTMLPages(static, holder, !fromDisk)
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet && r.Method != http.MethodHead {
@@ -31,15 +44,20 @@ func spaHandler(static fs.FS, cfg config.Config) http.HandlerFunc {
 			}
 		}
 
-		candidate, isHTML := resolveStaticPath(static, requestPath, dynamicRoutes)
+		candidate, isHTML := resolveStaticPath(static, requestPath, routes.get())
 		if candidate != "" {
 			if strings.HasSuffix(requestPath, ".ts") {
 				w.Header().Set("Content-Type", "text/typescript")
 			}
 			if isHTML {
 				w.Header().Set("Cache-Control", "no-cache")
-			} else {
-				w.Header().Set("Cache-Control", cacheControlForPath("/"+candidate))
+				pages.serve(w, r, candidate, http.StatusOK)
+				return
+			}
+			w.Header().Set("Cache-Control", cacheControlForPath("/"+candidate))
+			if asset := assets.lookup(candidate); asset != nil {
+				asset.serve(w, r, candidate)
+				return
 			}
 			r.URL.Path = "/" + candidate
 			fileServer.ServeHTTP(w, r)
@@ -48,9 +66,7 @@ func spaHandler(static fs.FS, cfg config.Config) http.HandlerFunc {
 
 		if fileExists(static, "404.html") {
 			w.Header().Set("Cache-Control", "no-cache")
-			w.WriteHeader(http.StatusNotFound)
-			r.URL.Path = "/404.html"
-			fileServer.ServeHTTP(w, r)
+			pages.serve(w, r, "404.html", http.StatusNotFound)
 			return
 		}
 
@@ -85,6 +101,77 @@ type dynamicRoute struct {
 	catchAlls    int
 }
 
+// routeTable holds the dynamic routes of the frontend. Requests read it without locking;
+// a frontend on disk gets a new table swapped in when its files change.
+type routeTable struct {
+	routes atomic.Pointer[[]dynamicRoute]
+}
+
+func newRouteTable(static fs.FS) *routeTable {
+	t := &routeTable{}
+	routes := buildDynamicRoutes(static)
+	t.routes.Store(&routes)
+	return t
+}
+
+// watchRouteTable rebuilds the table when a page is added, removed or renamed, which
+// changes the modification time of its directory. Every interval it stats the directories
+// seen by the last scan, and walks the tree again only when one of them changed.
+func watchRouteTable(ctx context.Context, static fs.FS, interval time.Duration) *routeTable {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	t := newRouteTable(static)
+	dirs := directoryTimes(static)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if !directoriesChanged(static, dirs) {
+				continue
+			}
+			dirs = directoryTimes(static)
+			routes := buildDynamicRoutes(static)
+			t.routes.Store(&routes)
+		}
+	}()
+	return t
+}
+
+func (t *routeTable) get() []dynamicRoute {
+	return *t.routes.Load()
+}
+
+// directoryTimes records the modification time of every directory in static.
+func directoryTimes(static fs.FS) map[string]time.Time {
+	dirs := map[string]time.Time{}
+	_ = fs.WalkDir(static, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if info, err := fs.Stat(static, path); err == nil {
+			dirs[path] = info.ModTime()
+		}
+		return nil
+	})
+	return dirs
+}
+
+func directoriesChanged(static fs.FS, dirs map[string]time.Time) bool {
+	for path, modTime := range dirs {
+		info, err := fs.Stat(static, path)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
 func buildDynamicRoutes(static fs.FS) []dynamicRoute {
 	var routes []dynamicRoute
 	_ = fs.WalkDir(static, ".", func(path string, entry fs.DirEntry, err error) error {
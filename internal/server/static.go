@@ -2,7 +2,13 @@ package server
 
 import (
 	"embed"
+	"errors"
 	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
 )
 
 // staticAssets holds the exported Studio frontend (copied into internal/server/static).
@@ -13,3 +19,51 @@ var staticAssets embed.FS
 func staticFS() (fs.FS, error) {
 	return fs.Sub(staticAssets, "static")
 }
+
+// frontendFS returns the frontend to serve and whether it is read from disk.
+func frontendFS(cfg config.Config) (fs.FS, bool, error) {
+	embedded, err := staticFS()
+	if err != nil || cfg.FrontendDir == "" {
+		return embedded, false, err
+	}
+	if info, err := os.Stat(cfg.FrontendDir); err != nil || !info.IsDir() {
+		return embedded, false, nil
+	}
+	slog.Info("serving frontend from disk", "dir", cfg.FrontendDir)
+	return overlayFS{upper: os.DirFS(cfg.FrontendDir), lower: embedded}, true, nil
+}
+
+// overlayFS reads files from upper, falling back to lower for files upper lacks.
+// Directory listings are merged.
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	file, err := o.upper.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.lower.Open(name)
+	}
+	return file, err
+}
+
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, upperErr := fs.ReadDir(o.upper, name)
+	lower, lowerErr := fs.ReadDir(o.lower, name)
+	if upperErr != nil && lowerErr != nil {
+		return nil, upperErr
+	}
+
+	seen := make(map[string]bool, len(upper))
+	for _, entry := range upper {
+		seen[entry.Name()] = true
+	}
+	for _, entry := range lower {
+		if !seen[entry.Name()] {
+			upper = append(upper, entry)
+		}
+	}
+	sort.Slice(upper, func(i, j int) bool { return upper[i].Name() < upper[j].Name() })
+	return upper, nil
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+func TestSPAHandlerServesFrontendDirOverEmbeddedBundle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("index.html", "<p>patched home</p>")
+
+	static := overlayFS{upper: os.DirFS(dir), lower: fstest.MapFS{
+		"index.html":           {Data: []byte("<p>embedded home</p>")},
+		"project/[ref].html":   {Data: []byte("<p>embedded project</p>")},
+		"_next/static/app.css": {Data: []byte("body{}")},
+	}}
+	handler := spaHandler(t.Context(), static, true, nil, config.NewHolder(config.Config{FrontendRescanInterval: 20 * time.Millisecond}, ""))
+	get := func(path string) string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Body.String()
+	}
+
+	if body := get("/"); !strings.Contains(body, "patched home") {
+		t.Fatalf("expected the file on disk to win, got %q", body)
+	}
+	if body := get("/project/default"); !strings.Contains(body, "embedded project") {
+		t.Fatalf("expected embedded pages for files missing on disk, got %q", body)
+	}
+	if body := get("/_next/static/app.css"); body != "body{}" {
+		t.Fatalf("expected embedded assets for files missing on disk, got %q", body)
+	}
+
+	writeFile("org/[slug].html", "<p>new organization page</p>")
+	deadline := time.Now().Add(5 * time.Second)
+	for body := get("/org/acme"); !strings.Contains(body, "new organization page"); body = get("/org/acme") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the new dynamic page to be routed, got %q", body)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+// NewHTTPSRedirect answers plain HTTP requests with a permanent redirect to the TLS listener.
+func NewHTTPSRedirect(cfg config.Config) http.Handler {
+	httpsPort := ""
+	if _, port, err := net.SplitHostPort(cfg.ListenAddress); err == nil && port != "443" {
+		httpsPort = port
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		if httpsPort != "" {
+			host += ":" + httpsPort
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Gouryella/supabase-studio-go/internal/config"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	cases := []struct {
+		listen string
+		host   string
+		want   string
+	}{
+		{listen: ":443", host: "studio.example.com", want: "https://studio.example.com/project/default?tab=sql"},
+		{listen: ":8443", host: "studio.example.com:8080", want: "https://studio.example.com:8443/project/default?tab=sql"},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/project/default?tab=sql", nil)
+		req.Host = tc.host
+		rec := httptest.NewRecorder()
+		NewHTTPSRedirect(config.Config{ListenAddress: tc.listen}).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Fatalf("expected 308, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != tc.want {
+			t.Fatalf("expected redirect to %q, got %q", tc.want, got)
+		}
+	}
+}
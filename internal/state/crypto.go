@@ -0,0 +1,168 @@
+package state
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	envelopeAlgorithm = "aes-256-gcm"
+	masterKeyMinSize  = 32
+)
+
+// envelope is an encrypted document. The document is sealed with a random data key, and
+// the data key with the key-encryption key named by KeyID, so rotating the master key only
+// rewraps one small key per document.
+type envelope struct {
+	Encryption string `json:"encryption"`
+	KeyID      string `json:"key_id"`
+	DataKey    []byte `json:"data_key"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type keyEncryptionKey struct {
+	id  string
+	aad []byte
+	gcm cipher.AEAD
+}
+
+// Keyring encrypts documents under its current key and decrypts documents under the current
+// or any previous key. A nil Keyring stores documents in plaintext.
+type Keyring struct {
+	keys []keyEncryptionKey
+}
+
+// LoadKeyring reads master keys from files. Each file holds at least 32 bytes of secret
+// material, such as the output of `openssl rand -base64 32`; surrounding whitespace is
+// ignored. It returns nil when current is empty.
+func LoadKeyring(current string, previous []string) (*Keyring, error) {
+	if strings.TrimSpace(current) == "" {
+		if len(previous) > 0 {
+			return nil, errors.New("previous state keys need a current key")
+		}
+		return nil, nil
+	}
+	keyring := &Keyring{}
+	for _, path := range append([]string{current}, previous...) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read state key: %w", err)
+		}
+		key, err := deriveKey(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("state key %s: %w", path, err)
+		}
+		keyring.keys = append(keyring.keys, key)
+	}
+	return keyring, nil
+}
+
+// KeyID identifies the current key without revealing it.
+func (k *Keyring) KeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.keys[0].id
+}
+
+func deriveKey(master string) (keyEncryptionKey, error) {
+	if len(master) < masterKeyMinSize {
+		return keyEncryptionKey{}, fmt.Errorf("must be at least %d bytes", masterKeyMinSize)
+	}
+	secret, err := hkdf.Key(sha256.New, []byte(master), nil, "supabase-studio-go state key", 32)
+	if err != nil {
+		return keyEncryptionKey{}, err
+	}
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return keyEncryptionKey{}, err
+	}
+	sum := sha256.Sum256(secret)
+	id := hex.EncodeToString(sum[:8])
+	return keyEncryptionKey{id: id, aad: []byte(id), gcm: gcm}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(gcm cipher.AEAD, plaintext, aad []byte) []byte {
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	return gcm.Seal(nonce, nonce, plaintext, aad)
+}
+
+func open(gcm cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+// encrypt returns data sealed under the current key, or data itself without a keyring.
+func (k *Keyring) encrypt(data []byte) ([]byte, error) {
+	if k == nil {
+		return data, nil
+	}
+	dataKey := make([]byte, 32)
+	rand.Read(dataKey)
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	current := k.keys[0]
+	return json.Marshal(envelope{
+		Encryption: envelopeAlgorithm,
+		KeyID:      current.id,
+		DataKey:    seal(current.gcm, dataKey, current.aad),
+		Ciphertext: seal(gcm, data, nil),
+	})
+}
+
+// decrypt returns the plaintext of data and whether it was encrypted. Plaintext documents,
+// such as those written before a key was configured, are returned as they are.
+func (k *Keyring) decrypt(data []byte) ([]byte, bool, error) {
+	var sealed envelope
+	if json.Unmarshal(data, &sealed) != nil || sealed.Encryption == "" {
+		return data, false, nil
+	}
+	if sealed.Encryption != envelopeAlgorithm {
+		return nil, true, fmt.Errorf("unsupported state encryption %q", sealed.Encryption)
+	}
+	if k == nil {
+		return nil, true, errors.New("state is encrypted but no state key is configured")
+	}
+	for _, key := range k.keys {
+		if key.id != sealed.KeyID {
+			continue
+		}
+		dataKey, err := open(key.gcm, sealed.DataKey, key.aad)
+		if err != nil {
+			return nil, true, fmt.Errorf("unwrap state data key: %w", err)
+		}
+		gcm, err := newGCM(dataKey)
+		if err != nil {
+			return nil, true, err
+		}
+		plaintext, err := open(gcm, sealed.Ciphertext, nil)
+		if err != nil {
+			return nil, true, fmt.Errorf("decrypt state: %w", err)
+		}
+		return plaintext, true, nil
+	}
+	return nil, true, fmt.Errorf("state is encrypted with key %s, which is not configured", sealed.KeyID)
+}
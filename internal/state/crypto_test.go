@@ -0,0 +1,99 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKey(t *testing.T, dir, name, key string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(key+"\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return path
+}
+
+func openEncrypted(t *testing.T, path, current string, previous ...string) Store {
+	t.Helper()
+	keyring, err := LoadKeyring(current, previous)
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+	store, err := Open(context.Background(), Options{Backend: BackendFile, FilePath: path, Keyring: keyring})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return store
+}
+
+func TestFileStoreEncryptsAndRotatesKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	oldKey := writeKey(t, dir, "old.key", strings.Repeat("o", 32))
+	newKey := writeKey(t, dir, "new.key", strings.Repeat("n", 32))
+
+	store := openEncrypted(t, path, oldKey)
+	doc, _ := store.Load(context.Background())
+	doc.Projects["default"] = Project{Name: "Secret Project"}
+	if _, err := store.Save(context.Background(), doc); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "Secret Project") {
+		t.Fatalf("expected the state file to be encrypted, got %s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	if _, err := openEncrypted(t, path, newKey).Load(context.Background()); err == nil {
+		t.Fatal("expected loading with only the new key to fail")
+	}
+	if _, err := (&fileStore{path: path}).Load(context.Background()); err == nil {
+		t.Fatal("expected loading an encrypted file without a key to fail")
+	}
+
+	rotated := openEncrypted(t, path, newKey, oldKey)
+	doc, err := rotated.Load(context.Background())
+	if err != nil || doc.Projects["default"].Name != "Secret Project" {
+		t.Fatalf("expected the previous key to decrypt the state, got %+v, %v", doc, err)
+	}
+	if _, err := rotated.Save(context.Background(), doc); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	doc, err = openEncrypted(t, path, newKey).Load(context.Background())
+	if err != nil || doc.Projects["default"].Name != "Secret Project" {
+		t.Fatalf("expected the state to be re-encrypted under the new key, got %+v, %v", doc, err)
+	}
+}
+
+func TestFileStoreEncryptsPlaintextStateOnLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(path, []byte(`{"project_name":"Legacy"}`), 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+
+	doc, err := openEncrypted(t, path, writeKey(t, dir, "state.key", strings.Repeat("k", 40))).Load(context.Background())
+	if err != nil || doc.Projects["default"].Name != "Legacy" {
+		t.Fatalf("expected the plaintext state to load, got %+v, %v", doc, err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "Legacy") || !strings.Contains(string(data), envelopeAlgorithm) {
+		t.Fatalf("expected the state file to be encrypted in place, got %s", data)
+	}
+}
+
+func TestLoadKeyringRejectsShortKeys(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadKeyring(writeKey(t, dir, "short.key", "too-short"), nil); err == nil {
+		t.Fatal("expected an error for a short key")
+	}
+	if keyring, err := LoadKeyring("", nil); keyring != nil || err != nil {
+		t.Fatalf("expected no keyring without a key file, got %v, %v", keyring, err)
+	}
+}
@@ -0,0 +1,232 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// schemaMigrations create and upgrade the studio schema. Pending entries run in order in one
+// transaction, and applied versions are recorded in studio.schema_migrations. Append new
+// entries, never edit applied ones.
+var schemaMigrations = []string{
+	`create table studio.state (
+		id smallint primary key default 1 check (id = 1),
+		document jsonb not null,
+		updated_at timestamptz not null default now()
+	)`,
+	`alter table studio.state add column revision bigint not null default 0`,
+}
+
+// notifyChannel is notified with the new revision whenever the document is saved.
+const notifyChannel = "studio_state"
+
+// postgresStore keeps the document in the studio schema of the managed database, so every
+// replica of the dashboard shares it. Saves are conditional on the revision the document was
+// loaded at, and notify the other replicas. The pool connects and the schema is migrated on
+// first use, so the database may come up after the dashboard. A plaintext document is
+// encrypted as soon as it is loaded with a keyring.
+type postgresStore struct {
+	pool *pgxpool.Pool
+	keys *Keyring
+
+	mu       sync.Mutex
+	migrated bool
+}
+
+func openPostgres(ctx context.Context, databaseURL string, keys *Keyring) (*postgresStore, error) {
+	if databaseURL == "" {
+		return nil, errors.New("the postgres state backend needs a database URL")
+	}
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresStore{pool: pool, keys: keys}, nil
+}
+
+func (s *postgresStore) ready(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.migrated {
+		return nil
+	}
+	if err := migrateSchema(ctx, s.pool); err != nil {
+		return err
+	}
+	s.migrated = true
+	return nil
+}
+
+// migrateSchema applies the pending schema migrations. An advisory lock keeps replicas
+// starting together from applying the same migration twice.
+func migrateSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext('studio.schema_migrations'))`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `create schema if not exists studio`); err != nil {
+			return fmt.Errorf("create studio schema: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `create table if not exists studio.schema_migrations (
+			version integer primary key,
+			applied_at timestamptz not null default now()
+		)`); err != nil {
+			return fmt.Errorf("create studio.schema_migrations: %w", err)
+		}
+
+		var applied int
+		if err := tx.QueryRow(ctx, `select coalesce(max(version), 0) from studio.schema_migrations`).Scan(&applied); err != nil {
+			return err
+		}
+		if applied > len(schemaMigrations) {
+			return fmt.Errorf("studio schema version %d is newer than this build supports (%d)", applied, len(schemaMigrations))
+		}
+		for i := applied; i < len(schemaMigrations); i++ {
+			if _, err := tx.Exec(ctx, schemaMigrations[i]); err != nil {
+				return fmt.Errorf("apply studio schema migration %d: %w", i+1, err)
+			}
+			if _, err := tx.Exec(ctx, `insert into studio.schema_migrations (version) values ($1)`, i+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *postgresStore) Load(ctx context.Context) (Document, error) {
+	if err := s.ready(ctx); err != nil {
+		return Document{}, err
+	}
+	var data []byte
+	var revision int64
+	err := s.pool.QueryRow(ctx, `select document, revision from studio.state where id = 1`).Scan(&data, &revision)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NewDocument(), nil
+	}
+	if err != nil {
+		return Document{}, err
+	}
+	plaintext, encrypted, err := s.keys.decrypt(data)
+	if err != nil {
+		return Document{}, err
+	}
+	doc, err := Decode(plaintext)
+	if err != nil {
+		return Document{}, err
+	}
+	doc.Revision = revision
+	if !encrypted && s.keys != nil {
+		// The content is unchanged, so the revision is kept and no replica is notified.
+		// Losing a race with a save is fine, since that save is encrypted.
+		sealed, err := s.encode(doc)
+		if err != nil {
+			return Document{}, err
+		}
+		if _, err := s.pool.Exec(ctx, `update studio.state set document = $1 where id = 1 and revision = $2`, sealed, revision); err != nil {
+			return Document{}, fmt.Errorf("encrypt plaintext state: %w", err)
+		}
+	}
+	return doc, nil
+}
+
+func (s *postgresStore) encode(doc Document) ([]byte, error) {
+	data, err := Encode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return s.keys.encrypt(data)
+}
+
+func (s *postgresStore) Save(ctx context.Context, doc Document) (int64, error) {
+	if err := s.ready(ctx); err != nil {
+		return 0, err
+	}
+	data, err := s.encode(doc)
+	if err != nil {
+		return 0, err
+	}
+
+	revision := doc.Revision + 1
+	err = pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `update studio.state set document = $1, revision = $2, updated_at = now()
+			where id = 1 and revision = $3`, data, revision, doc.Revision)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 && doc.Revision == 0 {
+			tag, err = tx.Exec(ctx, `insert into studio.state (id, document, revision) values (1, $1, $2)
+				on conflict (id) do nothing`, data, revision)
+			if err != nil {
+				return err
+			}
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrConflict
+		}
+		// Delivered when the transaction commits.
+		_, err = tx.Exec(ctx, `select pg_notify($1, $2)`, notifyChannel, strconv.FormatInt(revision, 10))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+// Watch listens for saves on a dedicated connection and reconnects with backoff when it is
+// lost. changed is also called after every (re)connect, since notifications sent while
+// disconnected are lost.
+func (s *postgresStore) Watch(ctx context.Context, changed func()) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := s.listen(ctx, changed, func() { backoff = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("lost the state change notifications, reconnecting", "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+func (s *postgresStore) listen(ctx context.Context, changed func(), connected func()) error {
+	if err := s.ready(ctx); err != nil {
+		return err
+	}
+	pooled, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The listening connection is taken out of the pool so that it is never handed to a query.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "listen "+notifyChannel); err != nil {
+		return err
+	}
+	connected()
+	changed()
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		changed()
+	}
+}
+
+func (s *postgresStore) Close() error {
+	s.pool.Close()
+	return nil
+}
@@ -0,0 +1,293 @@
+// Package state persists the settings changed through the dashboard, such as project names,
+// disk sizes and the PostgREST and pooler settings.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FormatVersion is the version of Document written by this build. Documents written by
+// older builds are upgraded by migrations when loaded.
+const FormatVersion = 2
+
+const (
+	BackendFile     = "file"
+	BackendPostgres = "postgres"
+)
+
+// ErrConflict is returned by Save when the document was saved by someone else since it was
+// loaded. Load it again and reapply the change.
+var ErrConflict = errors.New("state was changed concurrently")
+
+// Document is everything the dashboard has saved.
+type Document struct {
+	Version  int                `json:"version"`
+	Projects map[string]Project `json:"projects"`
+	// RevokedSessions maps the IDs of logged-out dashboard sessions to the time they would
+	// have expired, so that every replica refuses them until then.
+	RevokedSessions map[string]time.Time `json:"revoked_sessions,omitempty"`
+	// Revision counts the saves of the document. It is kept by the store, not in the
+	// document, and Save only accepts a document at the store's current revision.
+	Revision int64 `json:"-"`
+}
+
+// Project holds the saved settings of one project. Settings it leaves unset keep their
+// configured or built-in values.
+type Project struct {
+	Name            string         `json:"name,omitempty"`
+	DiskSizeGB      int            `json:"disk_size_gb,omitempty"`
+	PostgrestConfig map[string]any `json:"postgrest_config,omitempty"`
+	Pooling         map[string]any `json:"pooling,omitempty"`
+}
+
+// NewDocument returns an empty document in the current format.
+func NewDocument() Document {
+	return Document{Version: FormatVersion, Projects: map[string]Project{}}
+}
+
+// Clone returns a copy of d that can be changed without affecting d.
+func (d Document) Clone() Document {
+	clone := Document{
+		Version:         d.Version,
+		Revision:        d.Revision,
+		Projects:        make(map[string]Project, len(d.Projects)),
+		RevokedSessions: maps.Clone(d.RevokedSessions),
+	}
+	for ref, project := range d.Projects {
+		project.PostgrestConfig = maps.Clone(project.PostgrestConfig)
+		project.Pooling = maps.Clone(project.Pooling)
+		clone.Projects[ref] = project
+	}
+	return clone
+}
+
+// Store loads and saves the document. Save returns the document's new revision, or
+// ErrConflict when doc.Revision is not the store's current one.
+type Store interface {
+	Load(ctx context.Context) (Document, error)
+	Save(ctx context.Context, doc Document) (int64, error)
+	Close() error
+}
+
+// Watcher is implemented by stores shared between processes. Watch calls changed whenever
+// the document may have been saved by another process, until ctx is done.
+type Watcher interface {
+	Watch(ctx context.Context, changed func())
+}
+
+type Options struct {
+	// Backend is BackendFile or BackendPostgres.
+	Backend string
+	// FilePath is the file backend's document. Empty keeps the state in memory only.
+	FilePath string
+	// DatabaseURL is the Postgres backend's connection string.
+	DatabaseURL string
+	// Keyring encrypts the document at rest. Nil stores it in plaintext.
+	Keyring *Keyring
+}
+
+// Open returns the store for opts.Backend.
+func Open(ctx context.Context, opts Options) (Store, error) {
+	switch strings.ToLower(opts.Backend) {
+	case "", BackendFile:
+		if strings.TrimSpace(opts.FilePath) == "" {
+			return &memoryStore{doc: NewDocument()}, nil
+		}
+		return &fileStore{path: opts.FilePath, keys: opts.Keyring}, nil
+	case BackendPostgres:
+		return openPostgres(ctx, opts.DatabaseURL, opts.Keyring)
+	default:
+		return nil, fmt.Errorf("unknown state backend %q", opts.Backend)
+	}
+}
+
+// migrations upgrade a raw document by one version each: migrations[0] turns version 1
+// into version 2, and so on. Version 1 is the file written before the format was
+// versioned, which has no version field.
+var migrations = []func(raw map[string]any) error{
+	migrateUnversioned,
+}
+
+// migrateUnversioned moves the default project's settings, which version 1 kept at the top
+// level, into the projects map.
+func migrateUnversioned(raw map[string]any) error {
+	projects, _ := raw["projects"].(map[string]any)
+	if projects == nil {
+		projects = map[string]any{}
+	}
+	defaultProject := map[string]any{}
+	if name, _ := raw["project_name"].(string); strings.TrimSpace(name) != "" {
+		defaultProject["name"] = strings.TrimSpace(name)
+	}
+	if size, _ := raw["project_disk_size_gb"].(float64); size > 0 {
+		defaultProject["disk_size_gb"] = size
+	}
+	if len(defaultProject) > 0 {
+		projects["default"] = defaultProject
+	}
+	delete(raw, "project_name")
+	delete(raw, "project_disk_size_gb")
+	raw["projects"] = projects
+	return nil
+}
+
+// Decode parses a document in any format version up to FormatVersion. Empty input is an
+// empty document.
+func Decode(data []byte) (Document, error) {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return NewDocument(), nil
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Document{}, fmt.Errorf("decode state: %w", err)
+	}
+	version := 1
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > FormatVersion {
+		return Document{}, fmt.Errorf("state format version %d is newer than this build supports (%d)", version, FormatVersion)
+	}
+	if version < FormatVersion {
+		for ; version < FormatVersion; version++ {
+			if err := migrations[version-1](raw); err != nil {
+				return Document{}, fmt.Errorf("migrate state from version %d: %w", version, err)
+			}
+		}
+		raw["version"] = FormatVersion
+		migrated, err := json.Marshal(raw)
+		if err != nil {
+			return Document{}, err
+		}
+		data = migrated
+	}
+
+	doc := NewDocument()
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Document{}, fmt.Errorf("decode state: %w", err)
+	}
+	if doc.Projects == nil {
+		doc.Projects = map[string]Project{}
+	}
+	return doc, nil
+}
+
+// Encode serializes doc in the current format.
+func Encode(doc Document) ([]byte, error) {
+	doc.Version = FormatVersion
+	return json.Marshal(doc)
+}
+
+// fileStore keeps the document in a JSON file, replaced atomically on every save. The file
+// belongs to one process, so its revision is only counted in memory. A plaintext file is
+// encrypted as soon as it is loaded with a keyring.
+type fileStore struct {
+	path     string
+	keys     *Keyring
+	mu       sync.Mutex
+	revision int64
+}
+
+func (s *fileStore) Load(ctx context.Context) (Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		data = nil
+	} else if err != nil {
+		return Document{}, err
+	}
+	plaintext, encrypted, err := s.keys.decrypt(data)
+	if err != nil {
+		return Document{}, err
+	}
+	doc, err := Decode(plaintext)
+	if err != nil {
+		return Document{}, err
+	}
+	if !encrypted && s.keys != nil && len(data) > 0 {
+		if err := s.write(doc); err != nil {
+			return Document{}, fmt.Errorf("encrypt plaintext state: %w", err)
+		}
+	}
+	doc.Revision = s.revision
+	return doc, nil
+}
+
+func (s *fileStore) Save(ctx context.Context, doc Document) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc.Revision != s.revision {
+		return 0, ErrConflict
+	}
+	if err := s.write(doc); err != nil {
+		return 0, err
+	}
+	s.revision++
+	return s.revision, nil
+}
+
+// write replaces the file with doc. Only the owner can read it, since it may hold secrets.
+func (s *fileStore) write(doc Document) error {
+	data, err := Encode(doc)
+	if err != nil {
+		return err
+	}
+	if data, err = s.keys.encrypt(data); err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func (s *fileStore) Close() error {
+	return nil
+}
+
+// memoryStore is used when no state file is configured; settings last until the process
+// exits.
+type memoryStore struct {
+	mu  sync.Mutex
+	doc Document
+}
+
+func (s *memoryStore) Load(ctx context.Context) (Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.doc.Clone(), nil
+}
+
+func (s *memoryStore) Save(ctx context.Context, doc Document) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if doc.Revision != s.doc.Revision {
+		return 0, ErrConflict
+	}
+	s.doc = doc.Clone()
+	s.doc.Revision++
+	return s.doc.Revision, nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,93 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeMigratesUnversionedStateFile(t *testing.T) {
+	doc, err := Decode([]byte(`{"project_name":"My Project","project_disk_size_gb":16,"projects":{"staging":{"name":"Staging"}}}`))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if doc.Version != FormatVersion {
+		t.Fatalf("expected version %d, got %d", FormatVersion, doc.Version)
+	}
+	if got := doc.Projects["default"]; got.Name != "My Project" || got.DiskSizeGB != 16 {
+		t.Fatalf("expected the default project's settings to move into projects, got %+v", got)
+	}
+	if got := doc.Projects["staging"]; got.Name != "Staging" {
+		t.Fatalf("expected other projects to be kept, got %+v", got)
+	}
+}
+
+func TestDecodeRejectsNewerFormat(t *testing.T) {
+	if _, err := Decode([]byte(`{"version":99}`)); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("expected an error for a newer format, got %v", err)
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+	store, err := Open(context.Background(), Options{Backend: BackendFile, FilePath: path})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	doc, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load of a missing file: %v", err)
+	}
+	doc.Projects["default"] = Project{Name: "Saved", Pooling: map[string]any{"pool_mode": "session"}}
+	if _, err := store.Save(context.Background(), doc); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("expected the temporary file to be renamed away, got %v", err)
+	}
+
+	loaded, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := loaded.Projects["default"]; got.Name != "Saved" || got.Pooling["pool_mode"] != "session" {
+		t.Fatalf("expected the saved project back, got %+v", got)
+	}
+}
+
+func TestSaveRejectsStaleRevision(t *testing.T) {
+	store, err := Open(context.Background(), Options{Backend: BackendFile, FilePath: filepath.Join(t.TempDir(), "state.json")})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	first, _ := store.Load(context.Background())
+	second, _ := store.Load(context.Background())
+
+	first.Projects["default"] = Project{Name: "First"}
+	revision, err := store.Save(context.Background(), first)
+	if err != nil || revision != 1 {
+		t.Fatalf("expected the first save to succeed at revision 1, got %d, %v", revision, err)
+	}
+	second.Projects["default"] = Project{Name: "Second"}
+	if _, err := store.Save(context.Background(), second); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict for a stale document, got %v", err)
+	}
+
+	reloaded, _ := store.Load(context.Background())
+	if reloaded.Revision != 1 || reloaded.Projects["default"].Name != "First" {
+		t.Fatalf("expected the first save to be kept, got %+v", reloaded)
+	}
+}
+
+func TestOpenRejectsUnknownBackend(t *testing.T) {
+	if _, err := Open(context.Background(), Options{Backend: "redis"}); err == nil {
+		t.Fatal("expected an error for an unknown backend")
+	}
+	if _, err := Open(context.Background(), Options{Backend: BackendPostgres}); err == nil {
+		t.Fatal("expected an error for the postgres backend without a database URL")
+	}
+}
@@ -0,0 +1,148 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reloadCheckInterval bounds how often handshakes stat the certificate files.
+const reloadCheckInterval = time.Second
+
+type Options struct {
+	CertFile     string
+	KeyFile      string
+	MinVersion   string
+	ClientCAFile string
+}
+
+// New builds a server TLS config whose certificate is reloaded whenever the certificate
+// or key file changes on disk. When ClientCAFile is set, clients must present a
+// certificate signed by one of its CAs.
+func New(opts Options) (*tls.Config, error) {
+	if strings.TrimSpace(opts.CertFile) == "" || strings.TrimSpace(opts.KeyFile) == "" {
+		return nil, errors.New("both a TLS certificate and key file are required")
+	}
+
+	minVersion, err := ParseVersion(opts.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	reloader, err := NewCertReloader(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	if caFile := strings.TrimSpace(opts.ClientCAFile); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", caFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// ParseVersion accepts "1.0" through "1.3"; empty means TLS 1.2.
+func ParseVersion(value string) (uint16, error) {
+	switch strings.TrimPrefix(strings.TrimSpace(value), "TLS") {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q", value)
+	}
+}
+
+// CertReloader serves a key pair from disk and picks up rotated files, e.g. from cert-manager.
+// A broken rotation keeps the previous certificate in use.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	reloader := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastCheck) >= reloadCheckInterval {
+		c.lastCheck = time.Now()
+		if c.changed() {
+			if err := c.reload(); err != nil {
+				slog.Error("failed to reload TLS certificate, keeping the previous one", "cert", c.certFile, "error", err)
+			} else {
+				slog.Info("reloaded TLS certificate", "cert", c.certFile)
+			}
+		}
+	}
+	return c.cert, nil
+}
+
+func (c *CertReloader) changed() bool {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return false
+	}
+	return !certInfo.ModTime().Equal(c.certModTime) || !keyInfo.ModTime().Equal(c.keyModTime)
+}
+
+func (c *CertReloader) reload() error {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	c.cert = &cert
+	c.certModTime = certInfo.ModTime()
+	c.keyModTime = keyInfo.ModTime()
+	return nil
+}
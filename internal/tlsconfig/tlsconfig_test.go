@@ -0,0 +1,119 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeKeyPair(t *testing.T, dir, commonName string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func leafName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse leaf: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloaderPicksUpRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "first")
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("new reloader: %v", err)
+	}
+	cert, _ := reloader.GetCertificate(nil)
+	if got := leafName(t, cert); got != "first" {
+		t.Fatalf("expected first certificate, got %q", got)
+	}
+
+	writeKeyPair(t, dir, "second")
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(certFile, later, later)
+	_ = os.Chtimes(keyFile, later, later)
+	reloader.lastCheck = time.Time{}
+
+	cert, _ = reloader.GetCertificate(nil)
+	if got := leafName(t, cert); got != "second" {
+		t.Fatalf("expected rotated certificate, got %q", got)
+	}
+
+	if err := os.WriteFile(certFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatalf("corrupt cert: %v", err)
+	}
+	evenLater := later.Add(time.Minute)
+	_ = os.Chtimes(certFile, evenLater, evenLater)
+	reloader.lastCheck = time.Time{}
+
+	cert, _ = reloader.GetCertificate(nil)
+	if got := leafName(t, cert); got != "second" {
+		t.Fatalf("expected previous certificate to stay in use, got %q", got)
+	}
+}
+
+func TestNewConfiguresVersionAndClientCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "server")
+
+	config, err := New(Options{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3", ClientCAFile: certFile})
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if config.MinVersion != tls.VersionTLS13 {
+		t.Fatalf("expected TLS 1.3 minimum, got %x", config.MinVersion)
+	}
+	if config.ClientAuth != tls.RequireAndVerifyClientCert || config.ClientCAs == nil {
+		t.Fatal("expected client certificates to be required")
+	}
+
+	if _, err := New(Options{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.4"}); err == nil {
+		t.Fatal("expected unsupported TLS version to be rejected")
+	}
+	if _, err := New(Options{CertFile: certFile}); err == nil {
+		t.Fatal("expected missing key file to be rejected")
+	}
+}